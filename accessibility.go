@@ -0,0 +1,108 @@
+package main
+
+import (
+	"image/color"
+)
+
+// accessibility adapts the game for players who need less motion, more
+// contrast, larger text, or to see the cues they cannot hear.
+type accessibility struct {
+	// ReducedMotion turns off screen shake and drifting clouds.
+	ReducedMotion bool `json:"reduced_motion"`
+	// HighContrast swaps the orange bars for a colour-blind-safe palette
+	// with bordered tracks.
+	HighContrast bool `json:"high_contrast"`
+	// TextScale multiplies every UI font size.
+	TextScale float64 `json:"text_scale"`
+	// Captions shows the countdown voice and sound effects as text.
+	Captions bool `json:"captions"`
+}
+
+const (
+	minTextScale = 0.75
+	maxTextScale = 2.0
+)
+
+// palette is the set of colours the HUD bars are drawn in.
+type palette struct {
+	Fuel, Combo   color.Color
+	Track, Border color.Color
+	// Danger marks the fuel gauge's red zone.
+	Danger color.Color
+}
+
+var (
+	standardPalette = palette{
+		Fuel:   color.RGBA{255, 165, 0, 255},
+		Combo:  color.RGBA{255, 94, 0, 255},
+		Track:  color.RGBA{0, 0, 0, 180},
+		Danger: color.RGBA{150, 20, 20, 200},
+	}
+	// highContrastPalette takes blue, yellow and vermilion from the
+	// Okabe-Ito set, which stay distinct under every common colour vision
+	// deficiency.
+	highContrastPalette = palette{
+		Fuel:   color.RGBA{86, 180, 233, 255},
+		Combo:  color.RGBA{240, 228, 66, 255},
+		Track:  color.Black,
+		Border: color.White,
+		Danger: color.RGBA{213, 94, 0, 255},
+	}
+)
+
+func (a accessibility) palette() palette {
+	if a.HighContrast {
+		return highContrastPalette
+	}
+	return standardPalette
+}
+
+// textScale returns TextScale, treating unset as 1.
+func (a accessibility) textScale() float64 {
+	if a.TextScale <= 0 {
+		return 1
+	}
+	return a.TextScale
+}
+
+// loadFaces builds the UI fonts at the chosen scale. It can be called
+// again to rescale at runtime; the HUD must then be rebuilt.
+func loadFaces(scale float64) {
+	bigFont = fontLib.Face(72 * scale)
+	myFont = fontLib.Face(36 * scale)
+	smallFont = fontLib.Face(24 * scale)
+}
+
+const (
+	captionDuration = 1.6
+	maxCaptions     = 3
+)
+
+// caption is one line of text standing in for a sound.
+type caption struct {
+	text message
+	ttl  float64
+}
+
+// caption shows text for a sound that just played, if captions are on.
+func (g *Game) caption(text message) {
+	if !g.access.Captions {
+		return
+	}
+	g.captions = append(g.captions, caption{text: text, ttl: captionDuration})
+	if len(g.captions) > maxCaptions {
+		g.captions = g.captions[len(g.captions)-maxCaptions:]
+	}
+}
+
+// ageCaptions drops captions whose time is up.
+func (g *Game) ageCaptions(dt float64) {
+	kept := g.captions[:0]
+	for _, c := range g.captions {
+		c.ttl -= dt
+		if c.ttl > 0 {
+			kept = append(kept, c)
+		}
+	}
+	g.captions = kept
+}
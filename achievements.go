@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// achievement is a goal worked out from the run history, so earning one
+// needs no save data of its own. Its name and description are the catalog
+// messages "ach.<id>.name" and "ach.<id>.desc".
+type achievement struct {
+	id   string
+	done func(entries []HistoryEntry) bool
+}
+
+var achievements = []achievement{
+	{"first_flight", func(es []HistoryEntry) bool { return len(es) > 0 }},
+	{"jet_stream", anyRun(func(r sim.ResultStats) bool { return sim.ZoneIndexAt(r.Altitude) >= 1 })},
+	{"karman_line", anyRun(func(r sim.ResultStats) bool { return sim.ZoneIndexAt(r.Altitude) >= 4 })},
+	{"orbit", anyRun(func(r sim.ResultStats) bool { return sim.ZoneIndexAt(r.Altitude) >= 5 })},
+	{"combo", anyRun(func(r sim.ResultStats) bool { return r.MaxCombo >= 25 })},
+	{"fast_fingers", anyRun(func(r sim.ResultStats) bool { return r.AverageTPS >= 12 })},
+	{"hard", anyRun(func(r sim.ResultStats) bool {
+		return strings.HasPrefix(r.CategoryOrNormal(), string(sim.Hard)) && sim.ZoneIndexAt(r.Altitude) >= 2
+	})},
+	{"regular", func(es []HistoryEntry) bool { return len(es) >= 50 }},
+}
+
+// anyRun is met by any single launch that passes ok.
+func anyRun(ok func(sim.ResultStats) bool) func([]HistoryEntry) bool {
+	return func(es []HistoryEntry) bool {
+		for _, e := range es {
+			if ok(e.Result) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// unlockedAchievements reports which of achievements the history earns.
+func unlockedAchievements(entries []HistoryEntry) []bool {
+	got := make([]bool, len(achievements))
+	for i, a := range achievements {
+		got[i] = a.done(entries)
+	}
+	return got
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gitlab.com/Goodgis/go-game/leaderboard"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const (
+	boardQueueFile = "leaderboard-queue.json"
+	boardTimeout   = 10 * time.Second
+	boardRetry     = 30 * time.Second
+	boardRows      = 5
+)
+
+// boardTab is which ranking the results panel shows.
+type boardTab int
+
+const (
+	tabTop boardTab = iota
+	tabNearby
+	tabFriends
+	boardTabs
+)
+
+func (t boardTab) title() message {
+	switch t {
+	case tabNearby:
+		return msg("board.nearby")
+	case tabFriends:
+		return msg("board.friends")
+	}
+	return msg("board.top")
+}
+
+// boardView is the leaderboard state the results panel shows. It is built
+// off the game goroutine and handed over whole.
+type boardView struct {
+	status   message
+	rankings [boardTabs][]leaderboard.Standing
+}
+
+// boardLink submits finished runs to the leaderboard in the background so
+// a slow or missing server never stalls a frame.
+type boardLink struct {
+	client  *leaderboard.Client
+	player  string
+	friends []string
+
+	// mu guards the latest view; gen counts submissions so a slow reply
+	// for an earlier run cannot overwrite a later one's.
+	mu    sync.Mutex
+	view  boardView
+	gen   int
+	fresh bool
+}
+
+func newBoardLink(url, player string, friends []string) *boardLink {
+	b := &boardLink{
+		client:  leaderboard.NewClient(url, nil),
+		player:  player,
+		friends: friends,
+	}
+	if err := b.client.LoadQueue(savePath(boardQueueFile)); err != nil {
+		log.Println("failed to load leaderboard queue:", err)
+	}
+	go b.retry()
+	return b
+}
+
+// retry keeps sending queued runs while the game is open.
+func (b *boardLink) retry() {
+	for range time.Tick(boardRetry) {
+		if b.client.Pending() == 0 {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), boardTimeout)
+		if n, err := b.client.Flush(ctx); n > 0 {
+			log.Printf("sent %d queued leaderboard runs", n)
+		} else if err != nil && !errors.Is(err, leaderboard.ErrQueued) {
+			log.Println("leaderboard retry:", err)
+		}
+		cancel()
+	}
+}
+
+// submit checks r locally, sends it and fetches the rankings to show.
+func (b *boardLink) submit(r *sim.Replay) {
+	if r.Result == nil {
+		return
+	}
+	b.mu.Lock()
+	b.gen++
+	gen := b.gen
+	b.mu.Unlock()
+
+	b.publish(gen, boardView{status: msg("board.submitting")})
+	go func() {
+		// Checking first saves the server a run it would refuse.
+		rep, err := sim.Verify(r, *r.Result, sim.DefaultLimits())
+		if err != nil || !rep.OK() {
+			b.publish(gen, boardView{status: msg("board.unverified")})
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), boardTimeout)
+		defer cancel()
+
+		var v boardView
+		st, err := b.client.Submit(ctx, leaderboard.Submission{Player: b.player, Replay: r})
+		var rejected *leaderboard.RejectedError
+		switch {
+		case errors.Is(err, leaderboard.ErrQueued):
+			n := b.client.Pending()
+			b.publish(gen, boardView{status: countMsg("board.queued", n, n)})
+			return
+		case errors.As(err, &rejected):
+			v.status = msg("board.rejected", rejected.Reason)
+		case err != nil:
+			log.Println("leaderboard submit:", err)
+			v.status = msg("board.error")
+		case st.Rank == 0:
+			v.status = msg("board.unranked")
+		default:
+			v.status = msg("board.ranked", b.player, st.Rank)
+		}
+		// The server ranks rockets built from parts on their preset's
+		// board, which the run's own category does not name.
+		cat := r.Result.Category
+		if st.Entry.Category != "" {
+			cat = st.Entry.Category
+		}
+		if v.rankings[tabTop], err = b.client.Top(ctx, cat, boardRows); err == nil {
+			v.rankings[tabNearby], err = b.client.Nearby(ctx, cat, b.player, boardRows/2)
+		}
+		if err == nil {
+			v.rankings[tabFriends], err = b.client.Friends(ctx, cat, b.player, b.friends)
+		}
+		if err != nil {
+			log.Println("leaderboard rankings:", err)
+		}
+		b.publish(gen, v)
+	}()
+}
+
+// publish replaces the view unless a later submission has started.
+func (b *boardLink) publish(gen int, v boardView) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen == b.gen {
+		b.view, b.fresh = v, true
+	}
+}
+
+// pollBoard takes the newest view, if any, on the game goroutine.
+func (g *Game) pollBoard() {
+	if g.board == nil {
+		return
+	}
+	g.board.mu.Lock()
+	defer g.board.mu.Unlock()
+	if g.board.fresh {
+		g.boardView, g.board.fresh = g.board.view, false
+	}
+}
+
+// boardLines renders the selected ranking as text rows.
+func (g *Game) boardLines() []string {
+	var lines []string
+	for _, s := range g.boardView.rankings[g.boardTab] {
+		if len(lines) == boardRows {
+			break
+		}
+		marker := " "
+		if s.Entry.Player == g.board.player {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s#%-3d %-12s %8s", marker, s.Rank, s.Entry.Player, g.formatScore(sim.GoalOf(s.Entry.Category).Mode, s.Entry.Outcome().Score)))
+	}
+	return lines
+}
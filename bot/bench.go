@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"math"
+	"slices"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// Bench plays runs launches with agent under cfg, seeding run i with
+// seed+i. A Learner learns from each launch before the next.
+func Bench(cfg sim.Config, agent Agent, runs int, seed uint64) ([]sim.ResultStats, error) {
+	results := make([]sim.ResultStats, 0, runs)
+	learner, learns := agent.(Learner)
+	for i := 0; i < runs; i++ {
+		agent.Reset(seed + uint64(i))
+		r, err := sim.Run(cfg, seed+uint64(i), agent, nil)
+		if err != nil {
+			return results, err
+		}
+		if learns {
+			learner.Learn(r)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// Summary describes a distribution of values.
+type Summary struct {
+	N                  int
+	Mean, StdDev       float64
+	Min, P10, P50, P90 float64
+	Max                float64
+}
+
+// Summarize computes a Summary of values.
+func Summarize(values []float64) Summary {
+	if len(values) == 0 {
+		return Summary{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	var s Summary
+	s.N = len(sorted)
+	for _, v := range sorted {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+	for _, v := range sorted {
+		s.StdDev += (v - s.Mean) * (v - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(s.N))
+	s.Min, s.Max = sorted[0], sorted[s.N-1]
+	s.P10 = percentile(sorted, 0.10)
+	s.P50 = percentile(sorted, 0.50)
+	s.P90 = percentile(sorted, 0.90)
+	return s
+}
+
+func percentile(sorted []float64, p float64) float64 {
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
+
+// Bin is one bar of a histogram, counting values in [Lo, Hi).
+type Bin struct {
+	Lo, Hi float64
+	Count  int
+}
+
+// Histogram sorts values into n equal-width bins spanning their range. The
+// top bin includes its upper edge.
+func Histogram(values []float64, n int) []Bin {
+	if len(values) == 0 || n <= 0 {
+		return nil
+	}
+	lo, hi := slices.Min(values), slices.Max(values)
+	if hi == lo {
+		return []Bin{{Lo: lo, Hi: hi, Count: len(values)}}
+	}
+	width := (hi - lo) / float64(n)
+	bins := make([]Bin, n)
+	for i := range bins {
+		bins[i].Lo = lo + float64(i)*width
+		bins[i].Hi = lo + float64(i+1)*width
+	}
+	for _, v := range values {
+		i := min(int((v-lo)/width), n-1)
+		bins[i].Count++
+	}
+	return bins
+}
+
+// Altitudes extracts the altitude of each result.
+func Altitudes(results []sim.ResultStats) []float64 {
+	out := make([]float64, len(results))
+	for i, r := range results {
+		out[i] = r.Altitude
+	}
+	return out
+}
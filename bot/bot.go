@@ -0,0 +1,179 @@
+// Package bot has scripted and learning players. They drive a sim.Flight
+// through sim.Input exactly as the keyboard does, so they can play in the
+// window, fill in for testers, or run thousands of launches headlessly to
+// show how tuning changes shift the game's balance.
+package bot
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// Agent is a bot player.
+type Agent interface {
+	sim.Input
+	// Reset readies the agent for a new launch; seed drives any randomness
+	// so runs can be reproduced.
+	Reset(seed uint64)
+}
+
+// Learner is an Agent that improves from the outcome of each launch.
+type Learner interface {
+	Agent
+	Learn(r sim.ResultStats)
+}
+
+// Names lists the agents New knows.
+var Names = []string{"fixed", "combo", "human", "learner"}
+
+// New returns the named agent with its default settings.
+func New(name string) (Agent, error) {
+	switch name {
+	case "fixed":
+		return &Fixed{TPS: 8, Button: sim.Left}, nil
+	case "combo":
+		return &Combo{TPS: 12}, nil
+	case "human":
+		return NewHuman(), nil
+	case "learner":
+		return NewQLearner(), nil
+	}
+	return nil, fmt.Errorf("unknown bot %q, want one of %v", name, Names)
+}
+
+// Fixed taps one button at a steady rate, never building a combo.
+type Fixed struct {
+	TPS    float64
+	Button sim.Button
+
+	acc float64
+	buf [1]sim.Button
+}
+
+func (b *Fixed) Reset(uint64) { b.acc = 0 }
+
+func (b *Fixed) Presses(f *sim.Flight) []sim.Button {
+	if !f.CanCharge() {
+		return staging(f, stageReaction, b.buf[:])
+	}
+	b.acc += b.TPS / sim.Rate
+	if b.acc < 1 {
+		return nil
+	}
+	b.acc--
+	b.buf[0] = b.Button
+	return b.buf[:]
+}
+
+// Combo alternates buttons at a steady rate. Any rate above one tap per
+// combo window keeps the combo alive for the whole countdown.
+type Combo struct {
+	TPS float64
+
+	acc  float64
+	next sim.Button
+	buf  [1]sim.Button
+}
+
+func (b *Combo) Reset(uint64) { b.acc, b.next = 0, sim.Left }
+
+func (b *Combo) Presses(f *sim.Flight) []sim.Button {
+	if !f.CanCharge() {
+		return staging(f, stageReaction, b.buf[:])
+	}
+	b.acc += b.TPS / sim.Rate
+	if b.acc < 1 {
+		return nil
+	}
+	b.acc--
+	b.buf[0] = b.next
+	b.next = other(b.next)
+	return b.buf[:]
+}
+
+// Human taps like a person: it reacts late to the countdown, its intervals
+// wander around a mean rate, it sometimes hits the same button twice and
+// it slows down as it tires. It stops once the tank is in the red.
+type Human struct {
+	TPS      float64 // rate when fresh
+	Jitter   float64 // standard deviation of each interval, as a fraction of it
+	Slip     float64 // chance of repeating the last button
+	Fatigue  float64 // fraction of the rate lost per second of charging
+	Reaction float64 // seconds before the first tap
+
+	rng     *rand.Rand
+	started int
+	next    int
+	last    sim.Button
+	buf     [1]sim.Button
+}
+
+// NewHuman returns a player tapping a brisk but sustainable nine a second.
+func NewHuman() *Human {
+	return &Human{TPS: 9, Jitter: 0.25, Slip: 0.05, Fatigue: 0.03, Reaction: 0.3}
+}
+
+func (b *Human) Reset(seed uint64) {
+	b.rng = rand.New(rand.NewPCG(seed, 0x5eed))
+	b.started = -1
+	b.last = sim.NoButton
+}
+
+func (b *Human) Presses(f *sim.Flight) []sim.Button {
+	if !f.CanCharge() {
+		return staging(f, b.Reaction, b.buf[:])
+	}
+	if b.rng == nil {
+		b.Reset(0)
+	}
+	if f.InRedZone() {
+		return nil
+	}
+	if b.started < 0 {
+		b.started = f.StepIndex
+		b.next = f.StepIndex + b.steps(b.Reaction*(1+b.Jitter*b.rng.NormFloat64()))
+	}
+	if f.StepIndex < b.next {
+		return nil
+	}
+	elapsed := float64(f.StepIndex-b.started) / sim.Rate
+	rate := b.TPS * math.Max(0.3, 1-b.Fatigue*elapsed)
+	b.next = f.StepIndex + b.steps((1+b.Jitter*b.rng.NormFloat64())/rate)
+
+	press := other(b.last)
+	if b.last != sim.NoButton && b.rng.Float64() < b.Slip {
+		press = b.last
+	}
+	b.last = press
+	b.buf[0] = press
+	return b.buf[:]
+}
+
+// steps converts seconds to a whole number of steps, at least one.
+func (b *Human) steps(seconds float64) int {
+	return max(1, int(math.Round(seconds*sim.Rate)))
+}
+
+// stageReaction is how long the scripted bots take to stage once the
+// booster burns out.
+const stageReaction = 0.3
+
+// staging presses Stage once the flight has waited reaction seconds on a
+// spent booster, so bots fly two-stage launches as players do.
+func staging(f *sim.Flight, reaction float64, buf []sim.Button) []sim.Button {
+	if !f.StagePending() || f.StageWait < reaction {
+		return nil
+	}
+	buf[0] = sim.Stage
+	return buf[:1]
+}
+
+func other(b sim.Button) sim.Button {
+	if b == sim.Left {
+		return sim.Right
+	}
+	return sim.Left
+}
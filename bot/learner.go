@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"math/rand/v2"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// Actions the learner chooses between whenever it is free to tap.
+const (
+	actWait = iota
+	actLeft
+	actRight
+	numActions
+)
+
+// State features: the combo so far (capped), how much of the combo window
+// is left, and which button was pressed last.
+const (
+	comboBuckets = 5
+	timerBuckets = 4
+	numStates    = comboBuckets * timerBuckets * 3
+)
+
+// QLearner learns when and which button to press by Monte Carlo control
+// over a small table of states, rewarded with the altitude each launch
+// reaches. Like a person it cannot tap faster than MinInterval allows.
+type QLearner struct {
+	MinInterval  int     // steps between taps
+	Alpha        float64 // learning rate
+	Epsilon      float64 // chance of a random action, decayed per launch
+	EpsilonDecay float64
+	EpsilonMin   float64
+	// Scale divides altitude to give the reward, keeping values near 1.
+	Scale float64
+
+	q       [numStates][numActions]float64
+	visits  []decision
+	rng     *rand.Rand
+	lastTap int
+	buf     [1]sim.Button
+	// Episodes counts launches learned from.
+	Episodes int
+}
+
+type decision struct{ state, action int }
+
+// NewQLearner returns an untrained learner limited to 20 taps a second.
+func NewQLearner() *QLearner {
+	return &QLearner{
+		MinInterval:  3,
+		Alpha:        0.05,
+		Epsilon:      0.3,
+		EpsilonDecay: 0.995,
+		EpsilonMin:   0.01,
+		Scale:        10000,
+	}
+}
+
+func (b *QLearner) Reset(seed uint64) {
+	b.rng = rand.New(rand.NewPCG(seed, 0x1ea2))
+	b.visits = b.visits[:0]
+	b.lastTap = -b.MinInterval
+}
+
+func (b *QLearner) Presses(f *sim.Flight) []sim.Button {
+	if !f.CanCharge() {
+		return staging(f, stageReaction, b.buf[:])
+	}
+	if f.StepIndex-b.lastTap < b.MinInterval {
+		return nil
+	}
+	if b.rng == nil {
+		b.Reset(0)
+	}
+	s := state(f)
+	a := b.choose(s)
+	b.visits = append(b.visits, decision{s, a})
+	if a == actWait {
+		return nil
+	}
+	b.lastTap = f.StepIndex
+	b.buf[0] = sim.Left
+	if a == actRight {
+		b.buf[0] = sim.Right
+	}
+	return b.buf[:]
+}
+
+// Learn moves every decision taken this launch toward its outcome.
+func (b *QLearner) Learn(r sim.ResultStats) {
+	reward := r.Altitude / b.Scale
+	for _, d := range b.visits {
+		q := &b.q[d.state][d.action]
+		*q += b.Alpha * (reward - *q)
+	}
+	b.visits = b.visits[:0]
+	b.Epsilon = max(b.EpsilonMin, b.Epsilon*b.EpsilonDecay)
+	b.Episodes++
+}
+
+func (b *QLearner) choose(s int) int {
+	if b.rng.Float64() < b.Epsilon {
+		return b.rng.IntN(numActions)
+	}
+	best := 0
+	for a := 1; a < numActions; a++ {
+		if b.q[s][a] > b.q[s][best] {
+			best = a
+		}
+	}
+	return best
+}
+
+func state(f *sim.Flight) int {
+	combo := min(f.ComboCount, comboBuckets-1)
+	timer := 0
+	if w := f.ComboWindow(); w > 0 {
+		timer = min(int(f.ComboTimer/w*timerBuckets), timerBuckets-1)
+	}
+	return (combo*timerBuckets+timer)*3 + int(f.LastButton)
+}
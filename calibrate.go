@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const (
+	// calibrationFile keeps the measured latency between sessions.
+	calibrationFile = "calibration.json"
+	// maxLatency is sim.MaxLatency in milliseconds.
+	maxLatency = int(sim.MaxLatency * 1000)
+
+	calibrationBPM    = 100
+	calibrationLeadIn = 4  // clicks before taps count
+	calibrationTaps   = 12 // taps measured
+)
+
+// calibrationSave is the calibration file's contents.
+type calibrationSave struct {
+	Latency int `json:"latency_ms"`
+}
+
+// loadLatency sets the latency from the options, or from the last
+// calibration when the options leave it at zero.
+func (g *Game) loadLatency(o options) {
+	ms := o.Latency
+	if ms == 0 {
+		data, err := os.ReadFile(savePath(calibrationFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Println("failed to load latency calibration:", err)
+		}
+		var c calibrationSave
+		if err == nil {
+			if err := json.Unmarshal(data, &c); err != nil {
+				log.Println("failed to parse latency calibration:", err)
+			}
+		}
+		ms = c.Latency
+	}
+	g.latency = float64(max(-maxLatency, min(maxLatency, ms))) / 1000
+}
+
+func (g *Game) saveLatency() {
+	if !g.persist {
+		return
+	}
+	data, err := json.Marshal(calibrationSave{Latency: int(math.Round(g.latency * 1000))})
+	if err == nil {
+		err = os.WriteFile(savePath(calibrationFile), data, 0o644)
+	}
+	if err != nil {
+		log.Println("failed to save latency calibration:", err)
+	}
+}
+
+// calibration measures the latency by playing clicks on a steady beat
+// and timing the switch presses made along to them. The median offset of
+// the presses from the nearest click covers both the audio and the input
+// delay, so it is what the Music scheme takes off every press.
+type calibration struct {
+	clock   float64
+	clicks  int
+	offsets []float64
+	pulse   float64
+	done    bool
+}
+
+func (c *calibration) period() float64 {
+	return 60.0 / calibrationBPM
+}
+
+// update runs one tick; once enough presses are measured it sets and
+// saves the latency.
+func (c *calibration) update(g *Game) {
+	dt := g.clock.FrameDelta()
+	c.pulse = math.Max(0, c.pulse-dt)
+	if c.done {
+		return
+	}
+	c.clock += dt
+	for c.clock >= float64(c.clicks)*c.period() {
+		playSFX(sfxCountData)
+		c.clicks++
+		c.pulse = 0.12
+	}
+	if c.clicks <= calibrationLeadIn || !g.switchJustPressed() {
+		return
+	}
+	nearest := math.Round(c.clock/c.period()) * c.period()
+	c.offsets = append(c.offsets, c.clock-nearest)
+	if len(c.offsets) < calibrationTaps {
+		return
+	}
+	slices.Sort(c.offsets)
+	ms := int(math.Round(c.offsets[len(c.offsets)/2] * 1000))
+	g.latency = float64(max(-maxLatency, min(maxLatency, ms))) / 1000
+	g.saveLatency()
+	c.done = true
+}
+
+// status is the page's progress line.
+func (c *calibration) status(g *Game) string {
+	if c.done {
+		return g.lang.T("calib.result", g.lang.Number(g.latency*1000, 0))
+	}
+	if c.clicks <= calibrationLeadIn {
+		return g.lang.T("calib.listen")
+	}
+	return g.lang.T("calib.progress", len(c.offsets), calibrationTaps)
+}
+
+// draw flashes a light on every click.
+func (c *calibration) draw(dst *ebiten.Image) {
+	clr := color.RGBA{60, 60, 80, 255}
+	if c.pulse > 0 {
+		clr = color.RGBA{255, 230, 120, 255}
+	}
+	vector.DrawFilledCircle(dst, float32(screenWidth)/2, 150, 24, clr, true)
+}
+
+// calibrate opens the calibration page and starts the clicks. While it
+// runs the switch taps along instead of working the menu.
+func (t *titleScreen) calibrate(g *Game) {
+	c := &calibration{}
+	p := &menuPage{}
+	body := append(p.wrapped(3, 380, g.label("calib.help")), p.text(func() string { return c.status(g) }))
+	t.show(t.layout(g, p, "calib.title", body, nil))
+	t.calibrating = c
+}
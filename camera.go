@@ -0,0 +1,134 @@
+package main
+
+import (
+	"image"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// World coordinates are pixels with x running right and y running up from
+// the bottom edge of background.png, so a world y reads directly as height.
+const (
+	// rocketBaseY is the world height of the top of the rocket sprite while
+	// it sits on the pad.
+	rocketBaseY = 337.0
+	// cloudsY is the world height of the top edge of the cloud band.
+	cloudsY = 660.0
+	// recordMarkerY and recordLabelY offset the highscore marker sprite
+	// and its label from the saved altitude.
+	recordMarkerY = 385.0
+	recordLabelY  = 320.0
+
+	cameraFocusX     = 240.0
+	cameraFocusY     = 300.0
+	cameraFollowRate = 8.0
+	cameraZoomRate   = 2.0
+	cameraMinZoom    = 0.6
+)
+
+// Camera maps world coordinates onto the screen. The world point (X, Y) is
+// drawn at the focus row, scaled by Zoom around it.
+type Camera struct {
+	X, Y float64
+	Zoom float64
+
+	restY float64
+}
+
+func newCamera() Camera {
+	return Camera{X: cameraFocusX, Y: rocketBaseY, Zoom: 1, restY: rocketBaseY}
+}
+
+// Snap moves the camera onto y immediately at normal zoom.
+func (c *Camera) Snap(y float64) {
+	c.Y = y
+	c.Zoom = 1
+}
+
+// Follow eases the camera towards y and zoom with exponential smoothing,
+// independent of the step size.
+func (c *Camera) Follow(y, zoom, delta float64) {
+	c.Y += (y - c.Y) * (1 - math.Exp(-cameraFollowRate*delta))
+	c.Zoom += (zoom - c.Zoom) * (1 - math.Exp(-cameraZoomRate*delta))
+}
+
+// Parallax returns the camera as seen by a layer that moves at factor times
+// the speed of the foreground. Layers line up with the foreground at rest.
+func (c Camera) Parallax(factor float64) Camera {
+	c.Y = c.restY + (c.Y-c.restY)*factor
+	c.X = cameraFocusX + (c.X-cameraFocusX)*factor
+	c.Zoom = 1 + (c.Zoom-1)*factor
+	return c
+}
+
+// ToScreen converts a world point into screen pixels.
+func (c Camera) ToScreen(wx, wy float64) (sx, sy float64) {
+	return cameraFocusX + (wx-c.X)*c.Zoom, cameraFocusY - (wy-c.Y)*c.Zoom
+}
+
+// ToWorld converts a screen point into world coordinates.
+func (c Camera) ToWorld(sx, sy float64) (wx, wy float64) {
+	return c.X + (sx-cameraFocusX)/c.Zoom, c.Y - (sy-cameraFocusY)/c.Zoom
+}
+
+// Place sets op to draw an image whose top-left corner sits at world
+// (wx, wy), shaken by the given screen offset.
+func (c Camera) Place(op *ebiten.DrawImageOptions, wx, wy, shakeX, shakeY float64) {
+	op.GeoM.Translate(wx-c.X, c.Y-wy)
+	op.GeoM.Scale(c.Zoom, c.Zoom)
+	op.GeoM.Translate(cameraFocusX+shakeX, cameraFocusY+shakeY)
+}
+
+// targetZoom zooms out as the rocket approaches its top speed.
+func (g *Game) targetZoom() float64 {
+	f := g.flight
+	if f.SpeedMax <= 0 {
+		return 1
+	}
+	t := math.Max(0, math.Min(1, f.Speed/f.SpeedMax))
+	return 1 - (1-cameraMinZoom)*t
+}
+
+// layer is one parallax plane of the backdrop, drawn back to front.
+type layer struct {
+	name     string
+	parallax float64
+	draw     func(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64)
+}
+
+// newLayers builds the backdrop planes, back to front. bgSource is the
+// decoded background.png, which the procedural sky continues from.
+func newLayers(bgSource image.Image) []layer {
+	buildSky(bgSource)
+	return []layer{
+		{name: "sky", parallax: 1, draw: drawSky},
+		{name: "stars", parallax: 0.05, draw: drawStars},
+		{name: "earth", parallax: 0, draw: drawEarth},
+		{name: "haze", parallax: 0.9, draw: drawHaze},
+		{name: "airglow", parallax: 1, draw: drawAirglow},
+		{name: "ground", parallax: 1, draw: drawGround},
+		{name: "high clouds", parallax: 0.85, draw: drawHighClouds},
+		{name: "clouds", parallax: 1, draw: drawClouds},
+		{name: "jet stream", parallax: 1, draw: drawJetStream},
+		{name: "weather", parallax: 1, draw: drawWeather},
+	}
+}
+
+func drawGround(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	for j := -1.0; j <= 1; j++ {
+		op := &ebiten.DrawImageOptions{}
+		cam.Place(op, j*float64(screenWidth), float64(bg.Bounds().Dy()), shakeX, shakeY)
+		dst.DrawImage(bg, op)
+	}
+}
+
+func drawClouds(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	w := float64(clouds.Bounds().Dx())
+	for j := -1.0; j <= 2; j++ {
+		op := &ebiten.DrawImageOptions{}
+		cam.Place(op, g.bgoffset+j*w, cloudsY, shakeX, shakeY)
+		op.ColorScale.ScaleWithColor(g.cloudTint())
+		dst.DrawImage(clouds, op)
+	}
+}
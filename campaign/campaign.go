@@ -0,0 +1,221 @@
+// Package campaign loads the missions of the career mode from data files
+// and grades finished launches against them. Missions are JSON files in
+// one directory, played in file-name order, so new ones can be written
+// without touching the game's code.
+package campaign
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// MaxStars is the most stars a mission can award.
+const MaxStars = 3
+
+// Mission is one entry of the campaign.
+type Mission struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Brief string `json:"brief"`
+	// Requires lists missions that must be passed first; UnlockStars is
+	// how many stars the whole campaign must have earned.
+	Requires    []string `json:"requires"`
+	UnlockStars int      `json:"unlock_stars"`
+	// Map is where the mission sits on the mission-select map, from 0 to
+	// 1 across and down.
+	Map Point `json:"map"`
+
+	// Difficulty is the preset the launch starts from; Tuning overrides
+	// its fields by sim.Config's names, as for the custom difficulty.
+	Difficulty string          `json:"difficulty"`
+	Tuning     json.RawMessage `json:"tuning"`
+	Goal       sim.Goal        `json:"goal"`
+	// Ghost is a replay, relative to the mission file, flown alongside
+	// the player and measured by the ghost_margin stat.
+	Ghost string `json:"ghost"`
+
+	// Pass must all hold, and the goal be met, to complete the mission.
+	// Each of Stars then earns one star.
+	Pass  []Condition `json:"pass"`
+	Stars []Condition `json:"stars"`
+
+	config sim.Config
+	dir    string
+}
+
+// Point is a position on the mission map.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Config is the tuning the mission is flown with.
+func (m *Mission) Config() sim.Config {
+	return m.config
+}
+
+// GhostPath is the path of the ghost replay, or "" when there is none.
+func (m *Mission) GhostPath() string {
+	if m.Ghost == "" {
+		return ""
+	}
+	return filepath.Join(m.dir, m.Ghost)
+}
+
+// Run is a finished launch as a mission sees it: the result and, in
+// missions with a ghost, the ghost's.
+type Run struct {
+	Result sim.ResultStats
+	Ghost  *sim.ResultStats
+}
+
+// Grade reports whether r completes the mission and the stars it earns.
+func (m *Mission) Grade(r Run) (passed bool, stars int) {
+	if r.Result.OutcomeOrClassic().Missed {
+		return false, 0
+	}
+	for _, c := range m.Pass {
+		if !c.Met(r) {
+			return false, 0
+		}
+	}
+	for _, c := range m.Stars {
+		if c.Met(r) {
+			stars++
+		}
+	}
+	return true, stars
+}
+
+// Condition bounds one stat of a run. Either bound may be left out.
+type Condition struct {
+	Stat string   `json:"stat"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// Stats lists the stats a condition can bound.
+var Stats = []string{"altitude", "taps", "duration", "peak_speed", "max_combo", "tps", "ghost_margin"}
+
+// Value returns the condition's stat for r.
+func (c Condition) Value(r Run) float64 {
+	res := r.Result
+	switch c.Stat {
+	case "altitude":
+		return res.Altitude
+	case "taps":
+		return float64(res.TapCount)
+	case "duration":
+		return res.Duration
+	case "peak_speed":
+		return res.PeakSpeed
+	case "max_combo":
+		return float64(res.MaxCombo)
+	case "tps":
+		return res.AverageTPS
+	case "ghost_margin":
+		if r.Ghost == nil {
+			return 0
+		}
+		return res.Altitude - r.Ghost.Altitude
+	}
+	return 0
+}
+
+// Met reports whether r is within the condition's bounds.
+func (c Condition) Met(r Run) bool {
+	v := c.Value(r)
+	return (c.Min == nil || v >= *c.Min) && (c.Max == nil || v <= *c.Max)
+}
+
+func (c Condition) check(ghost bool) error {
+	if !slices.Contains(Stats, c.Stat) {
+		return fmt.Errorf("unknown stat %q, want one of %v", c.Stat, Stats)
+	}
+	if c.Min == nil && c.Max == nil {
+		return fmt.Errorf("condition on %s has no bound", c.Stat)
+	}
+	if c.Stat == "ghost_margin" && !ghost {
+		return fmt.Errorf("ghost_margin needs a ghost")
+	}
+	return nil
+}
+
+// Load reads a single mission file.
+func Load(path string) (*Mission, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &Mission{Difficulty: string(sim.Normal), dir: filepath.Dir(path)}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := m.prepare(); err != nil {
+		return nil, fmt.Errorf("mission %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// prepare checks the mission and works out its tuning.
+func (m *Mission) prepare() error {
+	if m.ID == "" {
+		return fmt.Errorf("no id")
+	}
+	d, err := sim.ParseDifficulty(m.Difficulty)
+	if err != nil {
+		return err
+	}
+	m.config = sim.Preset(d)
+	if len(m.Tuning) > 0 {
+		if err := json.Unmarshal(m.Tuning, &m.config); err != nil {
+			return fmt.Errorf("tuning: %w", err)
+		}
+	}
+	if err := m.Goal.Check(); err != nil {
+		return err
+	}
+	m.config.Goal = m.Goal
+	if len(m.Stars) > MaxStars {
+		return fmt.Errorf("%d stars, at most %d", len(m.Stars), MaxStars)
+	}
+	for _, c := range append(slices.Clone(m.Pass), m.Stars...) {
+		if err := c.check(m.Ghost != ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDir reads every mission in dir in file-name order and checks that
+// their ids are unique and their requirements exist.
+func LoadDir(dir string) ([]*Mission, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var missions []*Mission
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		m, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if seen[m.ID] {
+			return nil, fmt.Errorf("mission %s: duplicate id %q", path, m.ID)
+		}
+		for _, req := range m.Requires {
+			if !seen[req] {
+				return nil, fmt.Errorf("mission %s: requires %q, which is not an earlier mission", path, req)
+			}
+		}
+		seen[m.ID] = true
+		missions = append(missions, m)
+	}
+	return missions, nil
+}
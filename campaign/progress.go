@@ -0,0 +1,79 @@
+package campaign
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Progress is how far a player has got: the best stars earned on each
+// mission passed. A mission passed without stars is kept with zero.
+type Progress struct {
+	Stars map[string]int `json:"stars"`
+}
+
+// LoadProgress reads the progress file at path. A missing file is a fresh
+// campaign.
+func LoadProgress(path string) (Progress, error) {
+	p := Progress{Stars: make(map[string]int)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Progress{Stars: make(map[string]int)}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if p.Stars == nil {
+		p.Stars = make(map[string]int)
+	}
+	return p, nil
+}
+
+// Save writes the progress to path.
+func (p Progress) Save(path string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Passed reports whether the mission has been completed.
+func (p Progress) Passed(id string) bool {
+	_, ok := p.Stars[id]
+	return ok
+}
+
+// Total is the number of stars earned across the campaign.
+func (p Progress) Total() int {
+	n := 0
+	for _, s := range p.Stars {
+		n += s
+	}
+	return n
+}
+
+// Unlocked reports whether m can be played: its required missions are
+// passed and enough stars have been earned.
+func (p Progress) Unlocked(m *Mission) bool {
+	for _, req := range m.Requires {
+		if !p.Passed(req) {
+			return false
+		}
+	}
+	return p.Total() >= m.UnlockStars
+}
+
+// Record keeps a pass of the mission with id and reports whether it
+// improved on the best so far.
+func (p Progress) Record(id string, stars int) bool {
+	if best, ok := p.Stars[id]; ok && best >= stars {
+		return false
+	}
+	p.Stars[id] = stars
+	return true
+}
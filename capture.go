@@ -0,0 +1,202 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	gifpalette "image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	capturesDir = "captures"
+
+	// highlightDelay is the time between highlight frames in hundredths
+	// of a second, GIF's unit; highlightScale shrinks them to keep the
+	// ring of frames small.
+	highlightDelay = 7
+	highlightScale = 2
+	// highlightLinger is how long a record launch's highlight keeps
+	// recording once it lands, so it ends on the results panel.
+	highlightLinger = 2500 * time.Millisecond
+	// maxHighlight is the longest highlight -highlight accepts, in
+	// seconds; every second kept holds about 4 MB of frames.
+	maxHighlight = 20
+
+	noticeDuration = 3 * time.Second
+)
+
+// recorder takes screenshots and keeps the last seconds of flight as
+// downscaled frames, ready to be saved as an animated GIF. Files are
+// encoded and written in the background; the messages saying how that
+// went come back on done.
+type recorder struct {
+	frames [][]byte
+	next   int
+	filled int
+	last   time.Time
+	small  *ebiten.Image
+
+	shot   bool
+	saveAt time.Time
+	done   chan message
+}
+
+// newRecorder keeps seconds of highlight; zero keeps none, so only
+// screenshots can be taken.
+func newRecorder(seconds float64) *recorder {
+	n := int(seconds * 100 / highlightDelay)
+	return &recorder{frames: make([][]byte, n), done: make(chan message, 4)}
+}
+
+// saveLater saves the highlight after wait, or now if wait is zero.
+func (r *recorder) saveLater(wait time.Duration) {
+	if len(r.frames) > 0 {
+		r.saveAt = time.Now().Add(wait)
+	}
+}
+
+// reset forgets the highlight frames, so the next launch's highlight does
+// not open on the last one. A record's highlight still waiting to be saved
+// is saved first, cut short.
+func (r *recorder) reset() {
+	if !r.saveAt.IsZero() {
+		r.saveAt = time.Time{}
+		r.saveHighlight()
+	}
+	r.next, r.filled = 0, 0
+}
+
+// frame is called with every finished screen. It takes the screenshot
+// asked for, keeps a highlight frame when one is due and saves the
+// highlight when its time comes.
+func (r *recorder) frame(screen *ebiten.Image, keep bool) {
+	if r.shot {
+		r.shot = false
+		b := screen.Bounds()
+		img := image.NewRGBA(b)
+		screen.ReadPixels(img.Pix)
+		go r.write("shot", ".png", "capture.shot", func(f *os.File) error {
+			return png.Encode(f, img)
+		})
+	}
+	now := time.Now()
+	if keep && len(r.frames) > 0 && now.Sub(r.last) >= highlightDelay*10*time.Millisecond {
+		r.last = now
+		r.keep(screen)
+	}
+	if !r.saveAt.IsZero() && now.After(r.saveAt) {
+		r.saveAt = time.Time{}
+		r.saveHighlight()
+	}
+}
+
+// keep shrinks screen into the next slot of the ring.
+func (r *recorder) keep(screen *ebiten.Image) {
+	b := screen.Bounds()
+	w, h := b.Dx()/highlightScale, b.Dy()/highlightScale
+	if r.small == nil {
+		r.small = ebiten.NewImage(w, h)
+	}
+	r.small.Clear()
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(1.0/highlightScale, 1.0/highlightScale)
+	r.small.DrawImage(screen, op)
+	if r.frames[r.next] == nil {
+		r.frames[r.next] = make([]byte, 4*w*h)
+	}
+	r.small.ReadPixels(r.frames[r.next])
+	r.next = (r.next + 1) % len(r.frames)
+	r.filled = min(r.filled+1, len(r.frames))
+}
+
+// saveHighlight encodes the frames kept so far, oldest first.
+func (r *recorder) saveHighlight() {
+	if r.filled == 0 || r.small == nil {
+		return
+	}
+	b := r.small.Bounds()
+	frames := make([][]byte, 0, r.filled)
+	for i := range r.filled {
+		src := r.frames[(r.next-r.filled+i+len(r.frames))%len(r.frames)]
+		frames = append(frames, append([]byte(nil), src...))
+	}
+	go r.write("highlight", ".gif", "capture.highlight", func(f *os.File) error {
+		anim := &gif.GIF{}
+		for _, pix := range frames {
+			rgba := &image.RGBA{Pix: pix, Stride: 4 * b.Dx(), Rect: b}
+			p := image.NewPaletted(b, gifpalette.Plan9)
+			draw.FloydSteinberg.Draw(p, b, rgba, image.Point{})
+			anim.Image = append(anim.Image, p)
+			anim.Delay = append(anim.Delay, highlightDelay)
+		}
+		return gif.EncodeAll(f, anim)
+	})
+}
+
+// write creates a file in the captures directory named after kind and the
+// time to the millisecond, so shots taken in quick succession do not
+// overwrite each other, fills it with encode and reports the path, or the
+// error, as key.
+func (r *recorder) write(kind, ext, key string, encode func(f *os.File) error) {
+	dir := savePath(capturesDir)
+	path := filepath.Join(dir, kind+time.Now().Format("-20060102-150405.000")+ext)
+	err := os.MkdirAll(dir, 0o755)
+	if err == nil {
+		err = writeFile(path, encode)
+	}
+	if err != nil {
+		r.done <- msg("capture.failed", err)
+		return
+	}
+	r.done <- msg(key, path)
+}
+
+func writeFile(path string, encode func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := encode(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// updateCapture takes F12 as a screenshot and F9 as a highlight, and
+// shows how the last save went.
+func (g *Game) updateCapture() {
+	if g.JustPressed(ebiten.KeyF12) {
+		g.capture.shot = true
+	}
+	if g.JustPressed(ebiten.KeyF9) {
+		g.capture.saveLater(0)
+	}
+	select {
+	case m := <-g.capture.done:
+		g.notice, g.noticeUntil = m, time.Now().Add(noticeDuration)
+	default:
+	}
+}
+
+// drawCapture hands the finished frame to the recorder, then draws the
+// notice over it so captures do not show it.
+func (g *Game) drawCapture(screen *ebiten.Image) {
+	g.capture.frame(screen, g.scene != sceneTitle)
+	if time.Now().After(g.noticeUntil) {
+		return
+	}
+	lines := wrapText(smallFont, g.tr(g.notice), float64(screenWidth-20))
+	y := screenHeight - 10 - 24*len(lines)
+	for _, line := range lines {
+		y += 24
+		drawTextWithOutline(screen, line, smallFont, 10, y, color.White, color.Black)
+	}
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"gitlab.com/Goodgis/go-game/bot"
+	"gitlab.com/Goodgis/go-game/locale"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// options are the settings a session starts with. They come from the
+// defaults, then an optional JSON config file, then command-line flags.
+type options struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Fullscreen bool   `json:"fullscreen"`
+	Mute       bool   `json:"mute"`
+	SaveDir    string `json:"save_dir"`
+	Seed       uint64 `json:"seed"`
+	TPS        int    `json:"tps"`
+	// Bot names the bot that plays instead of the keyboard, if any.
+	Bot string `json:"bot"`
+
+	Difficulty string `json:"difficulty"`
+	// Custom is the tuning for the custom difficulty, in sim.Config's
+	// field names. Missing fields keep their Normal values.
+	Custom    *sim.Config `json:"custom"`
+	AutoTap   bool        `json:"auto_tap"`
+	WideCombo bool        `json:"wide_combo"`
+	// Scheme is how charge presses are made; see sim.Schemes.
+	Scheme string `json:"scheme"`
+	// Latency is how many milliseconds late presses arrive in the Music
+	// scheme; zero uses the last calibration.
+	Latency int `json:"latency_ms"`
+
+	// Mode is what launches are scored on; see sim.Modes. Target,
+	// Tolerance and TapBudget override the mode's standard goal when set.
+	Mode      string  `json:"mode"`
+	Target    float64 `json:"target"`
+	Tolerance float64 `json:"tolerance"`
+	TapBudget int     `json:"tap_budget"`
+
+	// Weather is "random" for a fresh sky each launch, drawn from the
+	// launch's seed, or one of sim.WeatherKinds to always fly in.
+	Weather string `json:"weather"`
+
+	accessibility
+
+	// Lang is the tag of the catalog in assets/lang the game is shown in;
+	// Units is "metric" or "imperial".
+	Lang  string `json:"lang"`
+	Units string `json:"units"`
+
+	// Leaderboard is the base URL of the leaderboard server; empty plays
+	// offline.
+	Leaderboard string   `json:"leaderboard"`
+	Player      string   `json:"player"`
+	Friends     []string `json:"friends"`
+
+	// Highlight is how many seconds of flight F9 and a new record save as
+	// an animated GIF; zero turns highlights off.
+	Highlight float64 `json:"highlight_seconds"`
+	// Debug turns on the developer overlay and console.
+	Debug bool `json:"debug"`
+}
+
+func defaultOptions() options {
+	custom := sim.Preset(sim.Custom)
+	return options{
+		Width:   screenWidth,
+		Height:  screenHeight,
+		SaveDir: ".",
+		Mode:    sim.Classic.String(),
+		Weather: randomWeather,
+		TPS:     ticksPerSecond,
+		Player:  "player",
+		Lang:    defaultLang,
+		Units:   locale.Metric.String(),
+
+		Highlight: 6,
+
+		Difficulty: string(sim.Normal),
+		Custom:     &custom,
+
+		accessibility: accessibility{TextScale: 1},
+	}
+}
+
+// config returns the tuning for the chosen difficulty and assists.
+func (o options) config() sim.Config {
+	d, _ := sim.ParseDifficulty(o.Difficulty)
+	cfg := sim.Preset(d)
+	if d == sim.Custom && o.Custom != nil {
+		cfg = *o.Custom
+		cfg.Assists = sim.Assists{}
+	}
+	cfg.Scheme, _ = sim.ParseScheme(o.Scheme)
+	cfg.Goal = o.goal()
+	return cfg.WithAssists(sim.Assists{AutoTap: o.AutoTap, WideCombo: o.WideCombo})
+}
+
+// goal returns the chosen mode's goal with any overrides applied.
+func (o options) goal() sim.Goal {
+	m, _ := sim.ParseMode(o.Mode)
+	g := sim.DefaultGoal(m)
+	switch m {
+	case sim.Target, sim.Precision:
+		if o.Target > 0 {
+			g.Altitude = o.Target
+		}
+		if m == sim.Precision && o.Tolerance > 0 {
+			g.Tolerance = o.Tolerance
+		}
+	case sim.Budget:
+		if o.TapBudget > 0 {
+			g.Taps = o.TapBudget
+		}
+	}
+	return g
+}
+
+const usage = `Usage: gorocket [flags] [command] [args]
+
+Commands:
+  (none)          start the game
+  replay <file>   play a recorded launch back in the window
+  render [render flags] <file>
+                  render a replay frame by frame to PNGs, a Y4M stream
+                  and a WAV soundtrack; it opens a window, so without a
+                  display run it under xvfb-run; render -h lists its flags
+  verify <file> [claimed.json]
+                  re-simulate a replay headlessly, check it reproduces the
+                  claimed result and that its tap timing looks human
+  stats           print the run history
+  reset-save      delete the highscore, run history, campaign
+                  progress and replays
+
+Flags:
+`
+
+// parseArgs resolves options and splits off the command and its arguments.
+func parseArgs(args []string, stderr io.Writer) (options, []string, error) {
+	fs := flag.NewFlagSet("gorocket", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	var flags options
+	def := defaultOptions()
+	configPath := fs.String("config", "", "read settings from this JSON `file` before applying flags")
+	fs.IntVar(&flags.Width, "width", def.Width, "window width in pixels")
+	fs.IntVar(&flags.Height, "height", def.Height, "window height in pixels")
+	fs.BoolVar(&flags.Fullscreen, "fullscreen", def.Fullscreen, "start in fullscreen")
+	fs.BoolVar(&flags.Mute, "mute", def.Mute, "disable music and sound effects")
+	fs.StringVar(&flags.SaveDir, "save-dir", def.SaveDir, "`directory` for the highscore, history and replays")
+	fs.Uint64Var(&flags.Seed, "seed", def.Seed, "random seed for every launch; 0 picks a new one per launch")
+	fs.IntVar(&flags.TPS, "tps", def.TPS, "update ticks per second")
+	fs.StringVar(&flags.Bot, "bot", def.Bot, fmt.Sprintf("let a bot play, one of %v", bot.Names))
+	fs.StringVar(&flags.Difficulty, "difficulty", def.Difficulty, fmt.Sprintf("one of %v; custom reads its tuning from the config file", sim.Difficulties))
+	fs.BoolVar(&flags.AutoTap, "auto-tap", def.AutoTap, "assist: holding a charge key keeps tapping")
+	fs.BoolVar(&flags.WideCombo, "wide-combo", def.WideCombo, "assist: widen the combo window")
+	fs.StringVar(&flags.Scheme, "scheme", def.Scheme, fmt.Sprintf("charge controls, one of %v", sim.Schemes))
+	fs.IntVar(&flags.Latency, "latency", def.Latency, "input and audio latency in `ms` for the music scheme; 0 uses the last calibration")
+	fs.StringVar(&flags.Mode, "mode", def.Mode, fmt.Sprintf("what launches are scored on, one of %v", sim.Modes))
+	fs.Float64Var(&flags.Target, "target", def.Target, "goal altitude in `metres` for the target and precision modes; 0 keeps the standard one")
+	fs.Float64Var(&flags.Tolerance, "tolerance", def.Tolerance, "half the precision band's width in `metres`; 0 keeps the standard one")
+	fs.IntVar(&flags.TapBudget, "tap-budget", def.TapBudget, "presses allowed in the budget mode; 0 keeps the standard one")
+	fs.StringVar(&flags.Weather, "weather", def.Weather, fmt.Sprintf("launch weather, one of %v", weatherSettings))
+	fs.BoolVar(&flags.ReducedMotion, "reduced-motion", def.ReducedMotion, "turn off screen shake and drifting clouds")
+	fs.BoolVar(&flags.HighContrast, "high-contrast", def.HighContrast, "colour-blind-safe, high-contrast bars")
+	fs.Float64Var(&flags.TextScale, "text-scale", def.TextScale, fmt.Sprintf("UI text size multiplier, %.2g to %.2g", minTextScale, maxTextScale))
+	fs.BoolVar(&flags.Captions, "captions", def.Captions, "show sounds and the countdown voice as text")
+	fs.StringVar(&flags.Lang, "lang", def.Lang, "language `tag`, one of the catalogs in "+langDir)
+	fs.StringVar(&flags.Units, "units", def.Units, "altitude units, metric or imperial")
+	fs.StringVar(&flags.Leaderboard, "leaderboard", def.Leaderboard, "leaderboard server `url` to submit runs to")
+	fs.StringVar(&flags.Player, "player", def.Player, "`name` runs are submitted under")
+	fs.Float64Var(&flags.Highlight, "highlight", def.Highlight, fmt.Sprintf("`seconds` of flight a highlight GIF keeps, up to %d; 0 turns highlights off", maxHighlight))
+	fs.BoolVar(&flags.Debug, "debug", def.Debug, "developer tools: F3 shows the debug overlay, ` opens the console")
+	friends := fs.String("friends", "", "comma-separated `players` for the friends ranking")
+	if err := fs.Parse(args); err != nil {
+		return options{}, nil, err
+	}
+
+	opts := def
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return options{}, nil, err
+		}
+		if err := json.Unmarshal(data, &opts); err != nil {
+			return options{}, nil, fmt.Errorf("parse config %s: %w", *configPath, err)
+		}
+	}
+	// Flags given explicitly win over the config file.
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "width":
+			opts.Width = flags.Width
+		case "height":
+			opts.Height = flags.Height
+		case "fullscreen":
+			opts.Fullscreen = flags.Fullscreen
+		case "mute":
+			opts.Mute = flags.Mute
+		case "save-dir":
+			opts.SaveDir = flags.SaveDir
+		case "seed":
+			opts.Seed = flags.Seed
+		case "tps":
+			opts.TPS = flags.TPS
+		case "bot":
+			opts.Bot = flags.Bot
+		case "difficulty":
+			opts.Difficulty = flags.Difficulty
+		case "auto-tap":
+			opts.AutoTap = flags.AutoTap
+		case "wide-combo":
+			opts.WideCombo = flags.WideCombo
+		case "scheme":
+			opts.Scheme = flags.Scheme
+		case "latency":
+			opts.Latency = flags.Latency
+		case "mode":
+			opts.Mode = flags.Mode
+		case "target":
+			opts.Target = flags.Target
+		case "tolerance":
+			opts.Tolerance = flags.Tolerance
+		case "tap-budget":
+			opts.TapBudget = flags.TapBudget
+		case "weather":
+			opts.Weather = flags.Weather
+		case "reduced-motion":
+			opts.ReducedMotion = flags.ReducedMotion
+		case "high-contrast":
+			opts.HighContrast = flags.HighContrast
+		case "text-scale":
+			opts.TextScale = flags.TextScale
+		case "captions":
+			opts.Captions = flags.Captions
+		case "lang":
+			opts.Lang = flags.Lang
+		case "units":
+			opts.Units = flags.Units
+		case "leaderboard":
+			opts.Leaderboard = flags.Leaderboard
+		case "player":
+			opts.Player = flags.Player
+		case "friends":
+			opts.Friends = strings.Split(*friends, ",")
+		case "highlight":
+			opts.Highlight = flags.Highlight
+		case "debug":
+			opts.Debug = flags.Debug
+		}
+	})
+
+	if err := opts.validate(); err != nil {
+		return options{}, nil, err
+	}
+	return opts, fs.Args(), nil
+}
+
+func (o options) validate() error {
+	if o.Width <= 0 || o.Height <= 0 {
+		return fmt.Errorf("window size %dx%d must be positive", o.Width, o.Height)
+	}
+	if o.TPS <= 0 {
+		return fmt.Errorf("tps %d must be positive", o.TPS)
+	}
+	if _, err := sim.ParseDifficulty(o.Difficulty); err != nil {
+		return err
+	}
+	if _, err := sim.ParseScheme(o.Scheme); err != nil {
+		return err
+	}
+	if o.Latency < -maxLatency || o.Latency > maxLatency {
+		return fmt.Errorf("latency %dms must be between %d and %d", o.Latency, -maxLatency, maxLatency)
+	}
+	if _, err := sim.ParseMode(o.Mode); err != nil {
+		return err
+	}
+	if o.Target < 0 || o.Tolerance < 0 || o.TapBudget < 0 {
+		return errors.New("target, tolerance and tap budget must not be negative")
+	}
+	if err := o.goal().Check(); err != nil {
+		return err
+	}
+	if !slices.Contains(weatherSettings, o.Weather) {
+		return fmt.Errorf("unknown weather %q, want one of %v", o.Weather, weatherSettings)
+	}
+	if o.TextScale < minTextScale || o.TextScale > maxTextScale {
+		return fmt.Errorf("text scale %.2f must be between %.2g and %.2g", o.TextScale, minTextScale, maxTextScale)
+	}
+	if _, err := locale.ParseUnits(o.Units); err != nil {
+		return err
+	}
+	if o.Bot != "" && !slices.Contains(bot.Names, o.Bot) {
+		return fmt.Errorf("unknown bot %q, want one of %v", o.Bot, bot.Names)
+	}
+	if o.Highlight < 0 || o.Highlight > maxHighlight {
+		return fmt.Errorf("highlight %gs must be between 0 and %d", o.Highlight, maxHighlight)
+	}
+	if o.Leaderboard != "" && o.Player == "" {
+		return errors.New("a player name is needed to submit to the leaderboard")
+	}
+	return nil
+}
+
+// run executes the command line and returns the process exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	opts, rest, err := parseArgs(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	saveDir = opts.SaveDir
+	ticksPerSecond = opts.TPS
+
+	cmd := ""
+	if len(rest) > 0 {
+		cmd, rest = rest[0], rest[1:]
+	}
+	needFile := func() (string, bool) {
+		if len(rest) != 1 {
+			fmt.Fprintf(stderr, "%s takes exactly one replay file\n", cmd)
+			return "", false
+		}
+		return rest[0], true
+	}
+
+	switch cmd {
+	case "":
+		err = runGame(opts, nil)
+	case "replay":
+		path, ok := needFile()
+		if !ok {
+			return 2
+		}
+		var r *sim.Replay
+		if r, err = sim.LoadReplay(path); err == nil {
+			err = runGame(opts, r)
+		}
+	case "render":
+		return renderCommand(opts, rest, stdout, stderr)
+	case "verify":
+		if len(rest) < 1 || len(rest) > 2 {
+			fmt.Fprintln(stderr, "verify takes a replay file and optionally a claimed result")
+			return 2
+		}
+		claimed := ""
+		if len(rest) == 2 {
+			claimed = rest[1]
+		}
+		return verifyCommand(rest[0], claimed, stdout, stderr)
+	case "stats":
+		var entries []HistoryEntry
+		if entries, err = loadHistory(); err == nil {
+			printHistory(stdout, entries)
+		}
+	case "reset-save":
+		err = resetSave(stdout)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n", cmd)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// verifyCommand re-simulates a replay and checks claimedPath's result, or
+// the one stored in the replay when claimedPath is empty, against it, then
+// inspects the tap timing. It exits non-zero if either check fails.
+func verifyCommand(path, claimedPath string, stdout, stderr io.Writer) int {
+	r, err := sim.LoadReplay(path)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var claimed sim.ResultStats
+	switch {
+	case claimedPath != "":
+		data, err := os.ReadFile(claimedPath)
+		if err == nil {
+			err = json.Unmarshal(data, &claimed)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "read claimed result: %v\n", err)
+			return 1
+		}
+	case r.Result != nil:
+		claimed = *r.Result
+	default:
+		fmt.Fprintln(stderr, "replay carries no recorded result; pass the claimed result as a second file")
+		return 1
+	}
+
+	rep, err := sim.Verify(r, claimed, sim.DefaultLimits())
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	got := rep.Reproduced
+	fmt.Fprintf(stdout, "simulated: %.0fm, %d taps, %.2f TPS, combo x%d\n", got.Altitude, got.TapCount, got.AverageTPS, got.MaxCombo)
+	for _, m := range rep.Mismatches {
+		fmt.Fprintln(stdout, "MISMATCH:", m)
+	}
+	for _, f := range rep.Flags {
+		fmt.Fprintln(stdout, "SUSPICIOUS:", f)
+	}
+	if !rep.OK() {
+		return 1
+	}
+	fmt.Fprintln(stdout, "OK: replay reproduces the claimed result with plausible input")
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const (
+	// simRate is the fixed rate the flight simulation steps at, whatever
+	// rate ebiten calls Update.
+	simRate = sim.Rate
+	// simStep is the length of one simulation step in seconds.
+	simStep = sim.StepDuration
+	// maxStepsPerFrame bounds how far the simulation catches up after a
+	// stall so a long hitch cannot snowball.
+	maxStepsPerFrame = 8
+)
+
+// ticksPerSecond is the rate ebiten calls Update and may be changed before
+// the game starts.
+var ticksPerSecond = 60
+
+// Clock converts ebiten ticks into fixed simulation steps. It accumulates
+// in integer units of 1/(tps*simRate) seconds so the number of steps run
+// after any sequence of ticks is exact.
+type Clock struct {
+	acc int
+	tps int
+}
+
+// Tick accounts for one ebiten Update call and returns how many simulation
+// steps should run before the next one.
+func (c *Clock) Tick() int {
+	c.tps = ebiten.TPS()
+	if c.tps <= 0 {
+		c.tps = ticksPerSecond
+	}
+	c.acc += simRate
+	steps := c.acc / c.tps
+	c.acc %= c.tps
+	if steps > maxStepsPerFrame {
+		steps = maxStepsPerFrame
+	}
+	return steps
+}
+
+// FrameDelta returns the length of one ebiten tick in seconds.
+func (c *Clock) FrameDelta() float64 {
+	if c.tps <= 0 {
+		return 1.0 / float64(ticksPerSecond)
+	}
+	return 1 / float64(c.tps)
+}
+
+// Alpha reports how far between the last two simulation steps the current
+// tick lies, for interpolating what is drawn.
+func (c *Clock) Alpha() float64 {
+	if c.tps <= 0 {
+		return 0
+	}
+	return float64(c.acc) / float64(c.tps)
+}
+
+// Reset drops any partial step.
+func (c *Clock) Reset() {
+	c.acc = 0
+}
@@ -0,0 +1,162 @@
+// Command autoplay plays thousands of launches headlessly with the bots and
+// prints how the altitudes they reach are distributed, so the effect of a
+// tuning change can be seen before anyone plays it.
+//
+//	autoplay -runs 5000 -bot all
+//	autoplay -bot human -combo-bonus 1 -power-max 900 -csv human.csv
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"gitlab.com/Goodgis/go-game/bot"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+func main() {
+	var tune sim.Config
+	difficulty := flag.String("difficulty", string(sim.Normal), fmt.Sprintf("preset to start from, one of %v", sim.Difficulties[:3]))
+	wideCombo := flag.Bool("wide-combo", false, "apply the wide combo window assist")
+	names := flag.String("bot", "all", fmt.Sprintf("comma-separated bots from %v, or all", bot.Names))
+	runs := flag.Int("runs", 1000, "launches per bot")
+	seed := flag.Uint64("seed", 1, "seed of the first launch")
+	bins := flag.Int("bins", 10, "histogram bins")
+	csvPath := flag.String("csv", "", "also write every result to this CSV `file`")
+	flag.Float64Var(&tune.ComboBonus, "combo-bonus", 0, "extra fuel per combo step")
+	flag.Float64Var(&tune.PowerMax, "power-max", 0, "fuel tank capacity")
+	flag.Float64Var(&tune.SpeedMax, "speed-max", 0, "top speed")
+	flag.Float64Var(&tune.BasePowerGain, "power-gain", 0, "fuel per press")
+	flag.Float64Var(&tune.ComboTimeout, "combo-timeout", 0, "seconds allowed between alternating presses")
+	flag.Float64Var(&tune.Thrust, "thrust", 0, "speed gained per second of burn")
+	flag.Float64Var(&tune.BurnRate, "burn-rate", 0, "fuel used per second of burn")
+	flag.Parse()
+
+	d, err := sim.ParseDifficulty(*difficulty)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Tuning flags override the preset only when given.
+	cfg := sim.Preset(d)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "combo-bonus":
+			cfg.ComboBonus = tune.ComboBonus
+		case "power-max":
+			cfg.PowerMax = tune.PowerMax
+		case "speed-max":
+			cfg.SpeedMax = tune.SpeedMax
+		case "power-gain":
+			cfg.BasePowerGain = tune.BasePowerGain
+		case "combo-timeout":
+			cfg.ComboTimeout = tune.ComboTimeout
+		case "thrust":
+			cfg.Thrust = tune.Thrust
+		case "burn-rate":
+			cfg.BurnRate = tune.BurnRate
+		}
+	})
+	cfg = cfg.WithAssists(sim.Assists{WideCombo: *wideCombo})
+
+	list := bot.Names
+	if *names != "all" {
+		list = strings.Split(*names, ",")
+	}
+
+	var out *csv.Writer
+	if *csvPath != "" {
+		f, err := os.Create(*csvPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = csv.NewWriter(f)
+		defer out.Flush()
+		out.Write([]string{"bot", "run", "altitude", "taps", "tps", "max_combo", "fuel", "zone", "failure"})
+	}
+
+	fmt.Printf("%d runs per bot on %s, combo bonus %.2f, power max %.0f\n\n", *runs, cfg.Category(), cfg.ComboBonus, cfg.PowerMax)
+	for _, name := range list {
+		agent, err := bot.New(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		results, err := bot.Bench(cfg, agent, *runs, *seed)
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		report(os.Stdout, name, results, *bins)
+		if out != nil {
+			for i, r := range results {
+				out.Write([]string{
+					name, strconv.Itoa(i),
+					strconv.FormatFloat(r.Altitude, 'f', 1, 64),
+					strconv.Itoa(r.TapCount),
+					strconv.FormatFloat(r.AverageTPS, 'f', 2, 64),
+					strconv.Itoa(r.MaxCombo),
+					strconv.FormatFloat(r.FuelCollected, 'f', 1, 64),
+					r.HighestZone,
+					string(r.Failure),
+				})
+			}
+		}
+	}
+}
+
+// report prints a bot's altitude summary and histogram.
+func report(w io.Writer, name string, results []sim.ResultStats, bins int) {
+	alts := bot.Altitudes(results)
+	s := bot.Summarize(alts)
+	fmt.Fprintf(w, "%s\n", name)
+	fmt.Fprintf(w, "  mean %.0fm  sd %.0fm  min %.0fm  p10 %.0fm  median %.0fm  p90 %.0fm  max %.0fm\n",
+		s.Mean, s.StdDev, s.Min, s.P10, s.P50, s.P90, s.Max)
+
+	zones := make(map[string]int)
+	for _, r := range results {
+		zones[r.HighestZone]++
+	}
+	fmt.Fprint(w, "  zones")
+	for _, z := range sim.Zones {
+		if n := zones[z.Name]; n > 0 {
+			fmt.Fprintf(w, "  %s %.1f%%", z.Name, 100*float64(n)/float64(len(results)))
+		}
+	}
+	fmt.Fprintln(w)
+
+	failures := make(map[sim.Failure]int)
+	for _, r := range results {
+		if r.Failure != sim.NoFailure {
+			failures[r.Failure]++
+		}
+	}
+	if len(failures) > 0 {
+		fmt.Fprint(w, "  failures")
+		for _, k := range sim.Failures {
+			if n := failures[k]; n > 0 {
+				fmt.Fprintf(w, "  %s %.1f%%", k, 100*float64(n)/float64(len(results)))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	const barWidth = 40
+	hist := bot.Histogram(alts, bins)
+	most := 0
+	for _, b := range hist {
+		most = max(most, b.Count)
+	}
+	for _, b := range hist {
+		bar := 0
+		if most > 0 {
+			bar = b.Count * barWidth / most
+		}
+		fmt.Fprintf(w, "  %6.0fm - %6.0fm %6d %s\n", b.Lo, b.Hi, b.Count, strings.Repeat("#", bar))
+	}
+	fmt.Fprintln(w)
+}
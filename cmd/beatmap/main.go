@@ -0,0 +1,140 @@
+// Command beatmap estimates the tempo and first beat of a music track and
+// writes the beat map the Music charge scheme grades presses against. The
+// estimate is a starting point: check it by ear and nudge the offset.
+//
+//	beatmap assets/sounds/bossa_nova.mp3
+//	beatmap -min-bpm 80 -max-bpm 140 -print track.mp3
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// frameRate is how many loudness frames a second of audio is cut into.
+const frameRate = 100
+
+func main() {
+	minBPM := flag.Float64("min-bpm", 60, "slowest tempo to consider")
+	maxBPM := flag.Float64("max-bpm", 200, "fastest tempo to consider")
+	bpm := flag.Float64("bpm", 0, "use this tempo and only estimate the offset")
+	printOnly := flag.Bool("print", false, "print the beat map instead of writing it beside the track")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: beatmap [flags] track.mp3")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || *minBPM <= 0 || *maxBPM <= *minBPM {
+		flag.Usage()
+		os.Exit(2)
+	}
+	track := flag.Arg(0)
+
+	onsets, length, err := readOnsets(track)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b := sim.Beat{BPM: *bpm}
+	if b.BPM <= 0 {
+		b.BPM = tempo(onsets, *minBPM, *maxBPM)
+	}
+	b.Offset = phase(onsets, b.BPM)
+	if err := b.Check(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: %.1fs, %.2f bpm, first beat at %.2fs\n", track, length, b.BPM, b.Offset)
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	data = append(data, '\n')
+	if *printOnly {
+		os.Stdout.Write(data)
+		return
+	}
+	path := strings.TrimSuffix(track, ".mp3") + ".beats.json"
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readOnsets decodes the track and returns its onset strength, the rise
+// in loudness from one frame to the next, and its length in seconds.
+func readOnsets(path string) ([]float64, float64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	stream, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 16-bit little-endian stereo; the left channel is enough for a beat.
+	samples := len(pcm) / 4
+	hop := stream.SampleRate() / frameRate
+	var loudness []float64
+	for i := 0; i+hop <= samples; i += hop {
+		sum := 0.0
+		for j := i; j < i+hop; j++ {
+			v := float64(int16(uint16(pcm[j*4]) | uint16(pcm[j*4+1])<<8))
+			sum += v * v
+		}
+		loudness = append(loudness, math.Sqrt(sum/float64(hop)))
+	}
+	onsets := make([]float64, len(loudness))
+	for i := 1; i < len(loudness); i++ {
+		onsets[i] = math.Max(0, loudness[i]-loudness[i-1])
+	}
+	return onsets, float64(samples) / float64(stream.SampleRate()), nil
+}
+
+// tempo picks the beat period, between the tempo bounds, at which the
+// onsets best line up with themselves.
+func tempo(onsets []float64, minBPM, maxBPM float64) float64 {
+	lo := int(60 * frameRate / maxBPM)
+	hi := int(math.Ceil(60 * frameRate / minBPM))
+	best, bestLag := -1.0, lo
+	for lag := max(lo, 1); lag <= hi && lag < len(onsets); lag++ {
+		sum := 0.0
+		for i := lag; i < len(onsets); i++ {
+			sum += onsets[i] * onsets[i-lag]
+		}
+		if sum /= float64(len(onsets) - lag); sum > best {
+			best, bestLag = sum, lag
+		}
+	}
+	return 60 * frameRate / float64(bestLag)
+}
+
+// phase finds the time, within the first beat, at which a grid of beats
+// at bpm collects the most onset strength.
+func phase(onsets []float64, bpm float64) float64 {
+	period := 60 * frameRate / bpm
+	best, bestStart := -1.0, 0
+	for start := 0; float64(start) < period; start++ {
+		sum := 0.0
+		for t := float64(start); int(t) < len(onsets); t += period {
+			sum += onsets[int(t)]
+		}
+		if sum > best {
+			best, bestStart = sum, start
+		}
+	}
+	return float64(bestStart) / frameRate
+}
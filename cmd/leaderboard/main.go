@@ -0,0 +1,108 @@
+// Command leaderboard serves the shared high-score board the game submits
+// verified runs to.
+//
+//	leaderboard -addr :8080 -store leaderboard.jsonl
+//	leaderboard -addr :8080 -sqlite leaderboard.db   (built with -tags sqlite)
+//	leaderboard -music assets/sounds/bossa_nova.mp3 -parts assets/hangar.json
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"
+
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/leaderboard"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// sqliteDriver names the database/sql driver registered by sqlite.go when
+// the binary is built with the sqlite tag; empty otherwise.
+var sqliteDriver string
+
+func main() {
+	addr := flag.String("addr", ":8080", "`address` to listen on")
+	file := flag.String("store", "leaderboard.jsonl", "JSON lines `file` to keep entries in")
+	sqlitePath := flag.String("sqlite", "", "keep entries in this SQLite `database` instead of -store")
+	music := flag.String("music", "assets/sounds/bossa_nova.mp3", "the game's music `track`, whose beat map Music scheme runs are checked against")
+	partsPath := flag.String("parts", "assets/hangar.json", "the game's hangar `catalog`, which rockets built from parts are rebuilt from")
+	flag.Parse()
+
+	beat, err := loadBeat(*music)
+	if err != nil {
+		log.Fatal(err)
+	}
+	parts, err := hangar.Load(*partsPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Printf("no hangar catalog at %s; runs on rockets built from parts will be refused", *partsPath)
+	case err != nil:
+		log.Fatal(err)
+	}
+	store, err := openStore(*file, *sqlitePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	srv := leaderboard.NewServer(store)
+	srv.Beat = beat
+	srv.Parts = parts
+	log.Printf("leaderboard listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
+
+// loadBeat reads the beat map beside track and measures the track's loop
+// length, as the game does. Like the game it counts sim.DefaultBeat when
+// there is no beat map, and a track that is missing does not loop.
+func loadBeat(track string) (sim.Beat, error) {
+	beat, err := sim.LoadBeatMap(strings.TrimSuffix(track, ".mp3") + ".beats.json")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		beat = sim.DefaultBeat
+	case err != nil:
+		return sim.Beat{}, err
+	}
+	data, err := os.ReadFile(track)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("no music at %s; Music runs must not loop", track)
+		return beat, nil
+	}
+	if err != nil {
+		return sim.Beat{}, err
+	}
+	stream, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return sim.Beat{}, fmt.Errorf("decode %s: %w", track, err)
+	}
+	beat.Length = float64(stream.Length()) / float64(4*stream.SampleRate())
+	return beat, nil
+}
+
+func openStore(file, sqlitePath string) (leaderboard.Store, error) {
+	if sqlitePath == "" {
+		return leaderboard.OpenFileStore(file)
+	}
+	if sqliteDriver == "" || !slices.Contains(sql.Drivers(), sqliteDriver) {
+		return nil, errors.New("this binary was built without SQLite support; rebuild with -tags sqlite")
+	}
+	db, err := sql.Open(sqliteDriver, sqlitePath)
+	if err != nil {
+		return nil, err
+	}
+	store, err := leaderboard.OpenSQLStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,11 @@
+//go:build sqlite
+
+package main
+
+// Building with -tags sqlite links the pure Go SQLite driver; fetch it first
+// with `go get modernc.org/sqlite`.
+import _ "modernc.org/sqlite"
+
+func init() {
+	sqliteDriver = "sqlite"
+}
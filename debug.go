@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// consoleLines is how much of the console's output stays on screen.
+const consoleLines = 6
+
+// debugTools are the overlay and console -debug turns on: F3 shows the
+// overlay and the backquote key opens the console. They are for
+// developers, so they are not translated.
+type debugTools struct {
+	overlay bool
+	open    bool
+	input   []rune
+	output  []string
+}
+
+// livePlayers are the audio players started for sounds, kept to count the
+// ones still playing.
+var livePlayers []*audio.Player
+
+// trackPlayer adds p to livePlayers, dropping the ones that have finished.
+func trackPlayer(p *audio.Player) *audio.Player {
+	livePlayers = slices.DeleteFunc(livePlayers, func(p *audio.Player) bool { return !p.IsPlaying() })
+	livePlayers = append(livePlayers, p)
+	return p
+}
+
+// playingCount is how many audio players are playing, music included.
+func playingCount() int {
+	n := 0
+	if musicPlayer != nil && musicPlayer.IsPlaying() {
+		n++
+	}
+	for _, p := range livePlayers {
+		if p.IsPlaying() {
+			n++
+		}
+	}
+	return n
+}
+
+// updateDebug handles the debug keys and, while the console is open,
+// typing into it. It reports whether the console took the keyboard, in
+// which case the game stays paused.
+func (g *Game) updateDebug() bool {
+	d := g.debug
+	if d == nil {
+		return false
+	}
+	if g.JustPressed(ebiten.KeyF3) {
+		d.overlay = !d.overlay
+	}
+	if g.JustPressed(ebiten.KeyBackquote) {
+		d.open = !d.open
+		return true
+	}
+	if !d.open {
+		return false
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		if r != '`' {
+			d.input = append(d.input, r)
+		}
+	}
+	switch {
+	case g.JustPressed(ebiten.KeyBackspace) && len(d.input) > 0:
+		d.input = d.input[:len(d.input)-1]
+	case g.JustPressed(ebiten.KeyEnter):
+		line := string(d.input)
+		d.input = d.input[:0]
+		d.print("> " + line)
+		if out := g.runCommand(strings.Fields(line)); out != "" {
+			d.print(out)
+		}
+	case g.JustPressed(ebiten.KeyEscape):
+		d.open = false
+	}
+	return true
+}
+
+func (d *debugTools) print(line string) {
+	d.output = append(d.output, line)
+	if len(d.output) > consoleLines {
+		d.output = d.output[len(d.output)-consoleLines:]
+	}
+}
+
+const consoleHelp = "set power|gravity|count <n>, tp <m>, spawn <effect> [n], land"
+
+// runCommand carries out one console command and returns what to print.
+// A launch the console has changed is not saved or submitted.
+func (g *Game) runCommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	if g.scene == sceneTitle {
+		return "start a launch first"
+	}
+	f := g.flight
+	number := func(i int) (float64, error) {
+		if len(args) <= i {
+			return 0, fmt.Errorf("%s needs a number", args[0])
+		}
+		return strconv.ParseFloat(args[i], 64)
+	}
+	switch args[0] {
+	case "help":
+		return consoleHelp
+	case "set":
+		if len(args) < 2 {
+			return "set what? power, gravity or count"
+		}
+		v, err := number(2)
+		if err != nil {
+			return err.Error()
+		}
+		switch args[1] {
+		case "power":
+			f.Power = min(max(v, 0), f.PowerMax)
+		case "gravity":
+			// The tuning keeps it for restarted launches too, which then
+			// rank under custom.
+			f.Gravity = v
+		case "count":
+			f.Count = int(v)
+		default:
+			return "unknown variable " + args[1]
+		}
+	case "tp":
+		v, err := number(1)
+		if err != nil {
+			return err.Error()
+		}
+		if !f.Launched {
+			return "only in flight"
+		}
+		f.Altitude, g.prevAltitude = v, v
+		g.camera.Snap(v + rocketBaseY)
+	case "spawn":
+		if len(args) < 2 || particleDefs[args[1]] == nil {
+			return "effects: " + strings.Join(slices.Sorted(maps.Keys(particleDefs)), ", ")
+		}
+		n := 0
+		if len(args) > 2 {
+			v, err := number(2)
+			if err != nil {
+				return err.Error()
+			}
+			n = int(v)
+		}
+		g.burst(args[1], nozzleX, f.Altitude+nozzleY, n)
+		return ""
+	case "land":
+		g.tampered = true
+		g.handleEvents(f.Land())
+		return "landed at " + g.length(g.lastResult.Altitude)
+	default:
+		return "unknown command; " + consoleHelp
+	}
+	g.tampered = true
+	return "ok"
+}
+
+// drawDebug draws the overlay and the console over the game, before any
+// capture is taken so screenshots for bug reports show them.
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	d := g.debug
+	if d == nil {
+		return
+	}
+	if d.overlay {
+		f := g.flight
+		lines := []string{
+			fmt.Sprintf("FPS %.1f  TPS %.1f", ebiten.ActualFPS(), ebiten.ActualTPS()),
+			fmt.Sprintf("alt %.2f  view %.2f", f.Altitude, g.viewAltitude()),
+			fmt.Sprintf("speed %.2f", f.Speed),
+			fmt.Sprintf("power %.1f/%.0f", f.Power, f.PowerMax),
+			fmt.Sprintf("combo %d  timer %.2f", f.ComboCount, f.ComboTimer),
+			fmt.Sprintf("particles %d", g.fx.Len()),
+			fmt.Sprintf("audio %d", playingCount()),
+		}
+		y := 160
+		for _, line := range lines {
+			drawTextWithOutline(screen, line, smallFont, 10, y, color.RGBA{120, 255, 120, 255}, color.Black)
+			y += 24
+		}
+	}
+	if d.open {
+		h := float32(24 * (consoleLines + 2))
+		vector.DrawFilledRect(screen, 0, 0, float32(screenWidth), h, color.RGBA{0, 0, 0, 200}, false)
+		y := 24
+		for _, line := range d.output {
+			drawTextWithOutline(screen, line, smallFont, 8, y, color.White, color.Black)
+			y += 24
+		}
+		prompt := "> " + string(d.input) + "_"
+		drawTextWithOutline(screen, prompt, smallFont, 8, int(h)-12, color.RGBA{255, 220, 120, 255}, color.Black)
+	}
+}
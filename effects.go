@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"gitlab.com/Goodgis/go-game/particles"
+)
+
+// nozzleX and nozzleY locate the engine nozzle relative to the rocket's
+// altitude, in world coordinates.
+const (
+	nozzleX = 240.0
+	nozzleY = 87.0
+
+	maxParticles = 2048
+)
+
+// initEffects creates the particle pool and the continuous emitters. It
+// runs after the sky is built so the pixel sprite exists.
+func (g *Game) initEffects() {
+	particleSprites = map[string]*ebiten.Image{
+		"smoke": smoke,
+		"pixel": whitePixel,
+	}
+	g.fx = particles.NewSystem(maxParticles, 1)
+	g.exhaust = g.fx.NewEmitter(particleDefs["exhaust"])
+	g.smoke = g.fx.NewEmitter(particleDefs["smoke"])
+	g.steam = g.fx.NewEmitter(particleDefs["steam"])
+}
+
+// updateEffects moves the emitters with the rocket drawn at altitude,
+// switches them on and off with the flight phase and advances the particle
+// system.
+func (g *Game) updateEffects(altitude, delta float64) {
+	f := g.flight
+	burning := f.Launched && !f.PowerDown && !f.StagePending()
+	for _, e := range []*particles.Emitter{g.exhaust, g.smoke} {
+		e.X, e.Y = nozzleX, altitude+nozzleY
+		e.Active = burning
+	}
+
+	g.steam.X, g.steam.Y = nozzleX, nozzleY-20
+	g.steam.Active = f.Charging && !f.Launched || g.scene == sceneTitle
+	g.steam.Scale = 1
+	if f.PowerMax > 0 {
+		g.steam.Scale += 3 * f.Power / f.PowerMax
+	}
+
+	g.fx.Wind = particleWind(f.Weather)
+	g.fx.Update(delta)
+}
+
+// burst fires a one-shot effect by name. It does nothing when the game has
+// no presentation, as when simulating a replay headlessly.
+func (g *Game) burst(name string, x, y float64, n int) {
+	if g.fx == nil {
+		return
+	}
+	g.fx.Burst(particleDefs[name], x, y, n)
+}
@@ -0,0 +1,183 @@
+package fonts
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const pageSize = 1024
+
+// glyph is one rune's fill and outline masks inside an atlas page. Both
+// masks share the same size and are offset from the pen position by off.
+type glyph struct {
+	fill    *ebiten.Image
+	outline *ebiten.Image
+	off     image.Point
+}
+
+// Atlas caches the glyphs of one face, each rasterized once together with
+// a dilated copy used as its outline. Drawing a string is then two image
+// draws per rune, which ebiten batches because every glyph shares a page.
+type Atlas struct {
+	face      font.Face
+	thickness int
+	kernel    []kernelTap
+	glyphs    map[rune]*glyph
+
+	pages      []*ebiten.Image
+	penX, penY int
+	rowHeight  int
+}
+
+type kernelTap struct {
+	dx, dy int
+	weight float64
+}
+
+func newAtlas(face font.Face, thickness int) *Atlas {
+	a := &Atlas{
+		face:      face,
+		thickness: thickness,
+		glyphs:    make(map[rune]*glyph),
+	}
+	// A disc of radius thickness with a one pixel anti-aliased rim.
+	r := thickness + 1
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			d := math.Hypot(float64(dx), float64(dy))
+			w := math.Min(1, float64(thickness)+1-d)
+			if w > 0 {
+				a.kernel = append(a.kernel, kernelTap{dx: dx, dy: dy, weight: w})
+			}
+		}
+	}
+	return a
+}
+
+// Face returns the face the atlas was built from.
+func (a *Atlas) Face() font.Face {
+	return a.face
+}
+
+// Measure returns the advance width of str in pixels.
+func (a *Atlas) Measure(str string) int {
+	return font.MeasureString(a.face, str).Round()
+}
+
+// Draw renders str with its baseline starting at (x, y). The outline pass
+// is skipped when outline is nil.
+func (a *Atlas) Draw(dst *ebiten.Image, str string, x, y int, clr, outline color.Color) {
+	if outline != nil && a.thickness > 0 {
+		a.drawPass(dst, str, x, y, outline, true)
+	}
+	a.drawPass(dst, str, x, y, clr, false)
+}
+
+func (a *Atlas) drawPass(dst *ebiten.Image, str string, x, y int, clr color.Color, outline bool) {
+	dot := fixed.I(x)
+	prev := rune(-1)
+	op := &ebiten.DrawImageOptions{}
+	for _, r := range str {
+		if prev >= 0 {
+			dot += a.face.Kern(prev, r)
+		}
+		g := a.glyph(r)
+		if g.fill != nil {
+			img := g.fill
+			if outline {
+				img = g.outline
+			}
+			op.GeoM.Reset()
+			op.GeoM.Translate(float64(dot.Round()+g.off.X), float64(y+g.off.Y))
+			op.ColorScale.Reset()
+			op.ColorScale.ScaleWithColor(clr)
+			dst.DrawImage(img, op)
+		}
+		adv, _ := a.face.GlyphAdvance(r)
+		dot += adv
+		prev = r
+	}
+}
+
+func (a *Atlas) glyph(r rune) *glyph {
+	if g, ok := a.glyphs[r]; ok {
+		return g
+	}
+	g := &glyph{}
+	a.glyphs[r] = g
+
+	dr, mask, maskp, _, ok := a.face.Glyph(fixed.Point26_6{}, r)
+	if !ok || dr.Empty() {
+		return g
+	}
+	pad := a.thickness + 1
+	w, h := dr.Dx()+2*pad, dr.Dy()+2*pad
+	cov := make([]float64, w*h)
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			_, _, _, alpha := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+			cov[(y+pad)*w+x+pad] = float64(alpha) / 0xffff
+		}
+	}
+
+	fill := make([]byte, 4*w*h)
+	border := make([]byte, 4*w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var best float64
+			for _, k := range a.kernel {
+				sx, sy := x+k.dx, y+k.dy
+				if sx < 0 || sy < 0 || sx >= w || sy >= h {
+					continue
+				}
+				if v := cov[sy*w+sx] * k.weight; v > best {
+					best = v
+				}
+			}
+			i := y*w + x
+			setWhite(fill, i, cov[i])
+			setWhite(border, i, best)
+		}
+	}
+
+	g.fill = a.alloc(w, h)
+	g.fill.WritePixels(fill)
+	g.outline = a.alloc(w, h)
+	g.outline.WritePixels(border)
+	g.off = image.Pt(dr.Min.X-pad, dr.Min.Y-pad)
+	return g
+}
+
+// setWhite stores premultiplied white at coverage v into pix at pixel i.
+func setWhite(pix []byte, i int, v float64) {
+	b := byte(math.Round(v * 0xff))
+	pix[4*i], pix[4*i+1], pix[4*i+2], pix[4*i+3] = b, b, b, b
+}
+
+// alloc reserves a w×h region on the current page using a shelf packer,
+// starting a new page when the current one is full.
+func (a *Atlas) alloc(w, h int) *ebiten.Image {
+	const gap = 1
+	if len(a.pages) == 0 || a.penX+w > pageSize {
+		a.penX = 0
+		a.penY += a.rowHeight + gap
+		a.rowHeight = 0
+	}
+	if len(a.pages) == 0 || a.penY+h > pageSize {
+		a.pages = append(a.pages, ebiten.NewImage(pageSize, pageSize))
+		a.penX, a.penY, a.rowHeight = 0, 0, 0
+	}
+	page := a.pages[len(a.pages)-1]
+	sub := page.SubImage(image.Rect(a.penX, a.penY, a.penX+w, a.penY+h)).(*ebiten.Image)
+	a.penX += w + gap
+	if h > a.rowHeight {
+		a.rowHeight = h
+	}
+	return sub
+}
@@ -0,0 +1,64 @@
+package fonts
+
+import (
+	"image"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// chainFace draws each rune with the first font in the chain that has a
+// glyph for it. Line metrics come from the first font.
+type chainFace struct {
+	fonts []*opentype.Font
+	faces []font.Face
+	pick  map[rune]int
+	buf   sfnt.Buffer
+}
+
+func (c *chainFace) face(r rune) font.Face {
+	i, ok := c.pick[r]
+	if !ok {
+		for j, f := range c.fonts {
+			if idx, err := f.GlyphIndex(&c.buf, r); err == nil && idx != 0 {
+				i = j
+				break
+			}
+		}
+		c.pick[r] = i
+	}
+	return c.faces[i]
+}
+
+func (c *chainFace) Close() error {
+	for _, f := range c.faces {
+		f.Close()
+	}
+	return nil
+}
+
+func (c *chainFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	return c.face(r).Glyph(dot, r)
+}
+
+func (c *chainFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	return c.face(r).GlyphBounds(r)
+}
+
+func (c *chainFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return c.face(r).GlyphAdvance(r)
+}
+
+// Kern only applies between runes drawn from the same font.
+func (c *chainFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	if f := c.face(r0); f == c.face(r1) {
+		return f.Kern(r0, r1)
+	}
+	return 0
+}
+
+func (c *chainFace) Metrics() font.Metrics {
+	return c.faces[0].Metrics()
+}
@@ -0,0 +1,123 @@
+// Package fonts loads the game's TrueType font at any number of sizes and
+// renders outlined text from glyphs rasterized once into a texture atlas.
+// Fallback fonts fill in scripts the main font has no glyphs for.
+package fonts
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+)
+
+// Library hands out faces and outlined atlases for a font and its
+// fallbacks.
+type Library struct {
+	font      *opentype.Font
+	fallbacks []*opentype.Font
+	faces     map[float64]font.Face
+	sizes     map[font.Face]float64
+	atlases   map[atlasKey]*Atlas
+}
+
+type atlasKey struct {
+	size      float64
+	thickness int
+}
+
+// Load reads and parses a TrueType or OpenType font file.
+func Load(path string) (*Library, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse builds a library from font file contents.
+func Parse(data []byte) (*Library, error) {
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse font: %w", err)
+	}
+	return &Library{
+		font:    f,
+		faces:   make(map[float64]font.Face),
+		sizes:   make(map[font.Face]float64),
+		atlases: make(map[atlasKey]*Atlas),
+	}, nil
+}
+
+// AddFallback reads a font to draw the runes that the main font and any
+// earlier fallbacks lack. Faces and atlases handed out before the call do
+// not use it; fetch them again.
+func (l *Library) AddFallback(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return fmt.Errorf("parse font %s: %w", path, err)
+	}
+	l.fallbacks = append(l.fallbacks, f)
+	clear(l.faces)
+	clear(l.sizes)
+	clear(l.atlases)
+	return nil
+}
+
+// Face returns the face for size points at 72 DPI, creating it on first use.
+func (l *Library) Face(size float64) font.Face {
+	if face, ok := l.faces[size]; ok {
+		return face
+	}
+	face := newFace(l.font, size)
+	if len(l.fallbacks) > 0 {
+		c := &chainFace{fonts: []*opentype.Font{l.font}, faces: []font.Face{face}, pick: make(map[rune]int)}
+		for _, f := range l.fallbacks {
+			c.fonts = append(c.fonts, f)
+			c.faces = append(c.faces, newFace(f, size))
+		}
+		face = c
+	}
+	l.faces[size] = face
+	l.sizes[face] = size
+	return face
+}
+
+// Outlined returns the atlas for text at size points with an outline
+// thickness pixels wide.
+func (l *Library) Outlined(size float64, thickness int) *Atlas {
+	key := atlasKey{size: size, thickness: thickness}
+	if a, ok := l.atlases[key]; ok {
+		return a
+	}
+	a := newAtlas(l.Face(size), thickness)
+	l.atlases[key] = a
+	return a
+}
+
+// AtlasFor returns the outlined atlas matching a face previously returned by
+// Face, or nil if the face did not come from this library.
+func (l *Library) AtlasFor(face font.Face, thickness int) *Atlas {
+	size, ok := l.sizes[face]
+	if !ok {
+		return nil
+	}
+	return l.Outlined(size, thickness)
+}
+
+func newFace(f *opentype.Font, size float64) font.Face {
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		// NewFace only fails on invalid options, which size alone cannot produce.
+		panic(fmt.Sprintf("fonts: new face at %vpt: %v", size, err))
+	}
+	return face
+}
@@ -0,0 +1,167 @@
+// Package hangar is the rocket's parts: the catalog of bodies, nose
+// cones, fins, engines, tanks, paints and exhaust colours, what each
+// weighs and does, what unlocks it, and the player profiles that own them
+// and remember the loadout. Parts are listed in a data file so new ones
+// can be added without touching the game's code; how each shape is drawn
+// is up to the game.
+package hangar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// Slot is one place on the rocket a part goes.
+type Slot string
+
+const (
+	Body    Slot = "body"
+	Nose    Slot = "nose"
+	Fins    Slot = "fins"
+	Engine  Slot = "engine"
+	Tank    Slot = "tank"
+	Paint   Slot = "paint"
+	Exhaust Slot = "exhaust"
+)
+
+// Slots lists every slot in menu order.
+var Slots = []Slot{Body, Nose, Fins, Engine, Tank, Paint, Exhaust}
+
+// Part is one choice for a slot. Its name is the catalog message
+// "part.<id>".
+type Part struct {
+	ID   string `json:"id"`
+	Slot Slot   `json:"slot"`
+	// Color is a paint's or an exhaust's colour; the stock part of those
+	// slots leaves it unset to keep the original look.
+	Color *[3]uint8 `json:"color,omitempty"`
+	// Achievement unlocks the part once earned; otherwise Price is what
+	// it costs in credits. A part with neither is stock, owned by all.
+	Achievement string `json:"achievement,omitempty"`
+	Price       int    `json:"price,omitempty"`
+	// Mass, Thrust, Fuel and Drag are what the part adds to the rocket;
+	// see sim.Rocket. Bodies, paints and exhausts are only for show.
+	Mass   float64 `json:"mass,omitempty"`
+	Thrust float64 `json:"thrust,omitempty"`
+	Fuel   float64 `json:"fuel,omitempty"`
+	Drag   float64 `json:"drag,omitempty"`
+}
+
+// Stock reports whether every player owns the part from the start.
+func (p Part) Stock() bool {
+	return p.Achievement == "" && p.Price == 0
+}
+
+// Catalog is every part, in menu order within each slot. The first part of
+// each slot is what an empty loadout entry means.
+type Catalog struct {
+	Parts []Part `json:"parts"`
+}
+
+// Load reads the parts file and checks that ids are unique, that every
+// slot starts with a stock part and that the stock parts make the stock
+// rocket.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Parts {
+		if !slices.Contains(Slots, p.Slot) {
+			return nil, fmt.Errorf("part %q: unknown slot %q, want one of %v", p.ID, p.Slot, Slots)
+		}
+		if p.ID == "" || seen[p.ID] {
+			return nil, fmt.Errorf("part %q: missing or duplicate id", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	for _, s := range Slots {
+		parts := c.InSlot(s)
+		if len(parts) == 0 || !parts[0].Stock() {
+			return nil, fmt.Errorf("slot %s must start with a stock part", s)
+		}
+	}
+	if r := c.Rocket(sim.Loadout{}); r != sim.StockRocket {
+		return nil, fmt.Errorf("stock parts make %+v, want %+v", r, sim.StockRocket)
+	}
+	return &c, nil
+}
+
+// InSlot lists the parts for s.
+func (c *Catalog) InSlot(s Slot) []Part {
+	var parts []Part
+	for _, p := range c.Parts {
+		if p.Slot == s {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+// Part returns the part with id in slot s. An empty or unknown id, as a
+// replay from a newer catalog may carry, gives the slot's stock part.
+func (c *Catalog) Part(s Slot, id string) Part {
+	parts := c.InSlot(s)
+	for _, p := range parts {
+		if p.ID == id {
+			return p
+		}
+	}
+	return parts[0]
+}
+
+// Get returns the part l has in slot s.
+func (c *Catalog) Get(l sim.Loadout, s Slot) Part {
+	return c.Part(s, *field(&l, s))
+}
+
+// Set returns l with p in its slot. The stock part is stored as empty so
+// loadouts that differ only in spelling it out compare equal.
+func (c *Catalog) Set(l sim.Loadout, p Part) sim.Loadout {
+	id := p.ID
+	if c.InSlot(p.Slot)[0].ID == id {
+		id = ""
+	}
+	*field(&l, p.Slot) = id
+	return l
+}
+
+// Rocket totals the parts of l.
+func (c *Catalog) Rocket(l sim.Loadout) sim.Rocket {
+	var r sim.Rocket
+	for _, s := range Slots {
+		p := c.Get(l, s)
+		r.Mass += p.Mass
+		r.Thrust += p.Thrust
+		r.Fuel += p.Fuel
+		r.Drag += p.Drag
+	}
+	return r
+}
+
+func field(l *sim.Loadout, s Slot) *string {
+	switch s {
+	case Body:
+		return &l.Body
+	case Nose:
+		return &l.Nose
+	case Fins:
+		return &l.Fins
+	case Engine:
+		return &l.Engine
+	case Tank:
+		return &l.Tank
+	case Paint:
+		return &l.Paint
+	}
+	return &l.Exhaust
+}
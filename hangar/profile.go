@@ -0,0 +1,109 @@
+package hangar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// MetresPerCredit is how high a launch must climb for each credit it
+// earns.
+const MetresPerCredit = 100
+
+var (
+	// ErrLocked is returned for a part that is earned, not bought.
+	ErrLocked = errors.New("part is unlocked by an achievement")
+	// ErrCredits is returned when a part costs more than the balance.
+	ErrCredits = errors.New("not enough credits")
+)
+
+// Profile is one player's hangar: the credits they have, the parts they
+// have bought and the loadout they fly.
+type Profile struct {
+	Credits int         `json:"credits"`
+	Owned   []string    `json:"owned,omitempty"`
+	Loadout sim.Loadout `json:"loadout"`
+}
+
+// LoadProfile reads the profile at path. A missing file is a new player.
+func LoadProfile(path string) (Profile, error) {
+	var p Profile
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Profile{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the profile to path, creating its directory.
+func (p Profile) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Earn adds the credits a finished launch is worth and returns them.
+func (p *Profile) Earn(r sim.ResultStats) int {
+	n := int(r.Altitude / MetresPerCredit)
+	p.Credits += n
+	return n
+}
+
+// Owns reports whether the player may fit part: it is stock, its
+// achievement is in earned, or it was bought.
+func (p *Profile) Owns(part Part, earned map[string]bool) bool {
+	switch {
+	case part.Stock():
+		return true
+	case part.Achievement != "":
+		return earned[part.Achievement]
+	}
+	return slices.Contains(p.Owned, part.ID)
+}
+
+// Buy spends credits on part.
+func (p *Profile) Buy(part Part) error {
+	switch {
+	case part.Stock() || slices.Contains(p.Owned, part.ID):
+		return nil
+	case part.Achievement != "":
+		return ErrLocked
+	case p.Credits < part.Price:
+		return ErrCredits
+	}
+	p.Credits -= part.Price
+	p.Owned = append(p.Owned, part.ID)
+	return nil
+}
+
+// Fitted is the profile's loadout with any part it no longer owns, as
+// after the run history is reset, swapped back for stock. A rocket that
+// then cannot lift off gets the stock engine and tank too.
+func (p *Profile) Fitted(c *Catalog, earned map[string]bool) sim.Loadout {
+	l := p.Loadout
+	for _, s := range Slots {
+		if part := c.Get(l, s); !p.Owns(part, earned) {
+			l = c.Set(l, c.InSlot(s)[0])
+		}
+	}
+	if c.Rocket(l).Check() != nil {
+		l.Engine, l.Tank = "", ""
+	}
+	return l
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const historyFile = "history.jsonl"
+
+// saveDir is where the highscore, run history and replays live.
+var saveDir = "."
+
+func savePath(name string) string {
+	return filepath.Join(saveDir, name)
+}
+
+// HistoryEntry is one finished launch in the run history.
+type HistoryEntry struct {
+	Time   time.Time       `json:"time"`
+	Result sim.ResultStats `json:"result"`
+	Replay string          `json:"replay,omitempty"`
+	// Mission is the id of the campaign mission flown, if any.
+	Mission string `json:"mission,omitempty"`
+}
+
+// appendHistory adds e as one JSON line to the history file.
+func appendHistory(e HistoryEntry) error {
+	if err := os.MkdirAll(saveDir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(savePath(historyFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// loadHistory reads every entry in the history file, oldest first. A
+// missing file is an empty history.
+func loadHistory() ([]HistoryEntry, error) {
+	f, err := os.Open(savePath(historyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []HistoryEntry
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e HistoryEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", historyFile, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// printHistory writes the run history as a table followed by totals.
+func printHistory(w io.Writer, entries []HistoryEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No runs recorded yet.")
+		return
+	}
+	fmt.Fprintf(w, "%-19s  %-20s  %9s  %-13s  %5s  %5s  %5s  %s\n", "When", "Difficulty", "Altitude", "Zone", "Taps", "TPS", "Combo", "Failure")
+	var total float64
+	failures := make(map[sim.Failure]int)
+	best := make(map[string]sim.Outcome)
+	var categories []string
+	for _, e := range entries {
+		r := e.Result
+		cat := r.CategoryOrNormal()
+		fmt.Fprintf(w, "%-19s  %-20s  %8.0fm  %-13s  %5d  %5.1f  x%-4d  %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), cat, r.Altitude, r.HighestZone, r.TapCount, r.AverageTPS, r.MaxCombo, r.Failure)
+		total += r.Altitude
+		if r.Failure != sim.NoFailure {
+			failures[r.Failure]++
+		}
+		o := r.OutcomeOrClassic()
+		if b, seen := best[cat]; !seen {
+			categories = append(categories, cat)
+			best[cat] = o
+		} else if sim.GoalOf(cat).Mode.Beats(o, b) {
+			best[cat] = o
+		}
+	}
+	fmt.Fprintf(w, "\n%d runs, average %.0fm\n", len(entries), total/float64(len(entries)))
+	if len(failures) > 0 {
+		fmt.Fprintf(w, "failures: %d engine, %d staging, %d structure\n",
+			failures[sim.EngineFailure], failures[sim.StagingFailure], failures[sim.StructuralFailure])
+	}
+	sort.Strings(categories)
+	for _, cat := range categories {
+		fmt.Fprintf(w, "best on %s: %s\n", categoryTitle(cat), scoreTitle(sim.GoalOf(cat).Mode, best[cat]))
+	}
+}
+
+// scoreTitle is the English form of an outcome for the command line.
+func scoreTitle(m sim.Mode, o sim.Outcome) string {
+	switch {
+	case o.Missed:
+		return "goal missed"
+	case m == sim.Target:
+		return fmt.Sprintf("%.0f taps", o.Score)
+	case m == sim.Precision:
+		return fmt.Sprintf("%.0fm off", o.Score)
+	}
+	return fmt.Sprintf("%.0fm", o.Score)
+}
+
+// recordRun persists a finished launch: its replay, a history line, the
+// credits it earns and, if it is a new best outside the campaign, the
+// highscore.
+func (g *Game) recordRun() {
+	entry := HistoryEntry{Time: time.Now(), Result: g.lastResult}
+	if g.mission != nil {
+		entry.Mission = g.mission.mission.ID
+	}
+	if g.recording != nil {
+		result := g.lastResult
+		g.recording.Result = &result
+		g.recording.Recorded = entry.Time
+		name, err := saveReplay(g.recording)
+		if err != nil {
+			log.Println("failed to save replay:", err)
+		} else {
+			entry.Replay = name
+		}
+	}
+	if err := appendHistory(entry); err != nil {
+		log.Println("failed to append run history:", err)
+	}
+	g.credits = g.profile.Earn(g.lastResult)
+	g.saveProfile()
+	if g.mission == nil && g.isRecord(g.lastResult) {
+		g.saved_highscore = g.lastResult.OutcomeOrClassic().Score
+		g.highscores[g.lastResult.Category] = g.saved_highscore
+		if err := g.saveHighscore(); err != nil {
+			log.Println("failed to save highscore:", err)
+		}
+	}
+}
+
+// isRecord reports whether r beats the best score kept for its category.
+// A missed goal is never a record.
+func (g *Game) isRecord(r sim.ResultStats) bool {
+	o := r.OutcomeOrClassic()
+	if o.Missed {
+		return false
+	}
+	best, ok := g.highscores[r.Category]
+	if !ok {
+		return o.Score > 0 || sim.GoalOf(r.Category).Mode.LowerIsBetter()
+	}
+	return sim.GoalOf(r.Category).Mode.Beats(o, sim.Outcome{Score: best})
+}
+
+// saveReplay writes r into the replays directory, named after the time it
+// was recorded, and returns the path.
+func saveReplay(r *sim.Replay) (string, error) {
+	dir := savePath("replays")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, r.Recorded.Format("run-20060102-150405.000")+".json")
+	return name, r.Save(name)
+}
+
+// resetSave deletes the highscore, run history, campaign progress, hangar
+// profiles and saved replays.
+func resetSave(w io.Writer) error {
+	for _, name := range []string{highscoreFile, historyFile, progressFile, profilesDir, "replays"} {
+		path := savePath(name)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "removed", path)
+	}
+	return nil
+}
+
+// categoryTitle turns a score category such as "hard+assist/rhythm" into
+// the words the player sees.
+func categoryTitle(cat string) string {
+	cat, goal, _ := strings.Cut(cat, ":")
+	cat, scheme, _ := strings.Cut(cat, "/")
+	name, assisted := strings.CutSuffix(cat, "+assist")
+	name = capitalize(name)
+	if assisted {
+		name += " (assisted)"
+	}
+	if scheme != "" {
+		name += ", " + capitalize(scheme)
+	}
+	if g, err := sim.ParseGoalKey(goal); err == nil && g.Mode != sim.Classic {
+		name += ", " + goalTitle(g)
+	}
+	return name
+}
+
+// goalTitle is the English description of a goal for the command line.
+func goalTitle(g sim.Goal) string {
+	switch g.Mode {
+	case sim.Target:
+		return fmt.Sprintf("Target %.0fm", g.Altitude)
+	case sim.Precision:
+		return fmt.Sprintf("Precision %.0f±%.0fm", g.Altitude, g.Tolerance)
+	case sim.Budget:
+		return fmt.Sprintf("Budget %d taps", g.Taps)
+	}
+	return "Classic"
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"gitlab.com/Goodgis/go-game/sim"
+	"gitlab.com/Goodgis/go-game/ui"
+)
+
+// hud holds the retained widget trees for the in-flight HUD and the flight
+// results panel. Only the widgets that change every frame are kept as fields.
+type hud struct {
+	root    *ui.Root
+	results *ui.Root
+
+	status     *ui.Label
+	goal       *ui.Label
+	forecast   *ui.Label
+	warning    *ui.Label
+	fuel       *ui.ProgressBar
+	combo      *ui.Panel
+	comboLabel *ui.Label
+	comboBar   *ui.ProgressBar
+	tpsLabel   *ui.Label
+	banner     *ui.Panel
+	zoneLabel  *ui.Label
+
+	title      *ui.Label
+	hint       *ui.Label
+	entering   *ui.Label
+	statLines  []*ui.Label
+	captions   []*ui.Label
+	captionBox *ui.Panel
+
+	board       *ui.Panel
+	boardTitle  *ui.Label
+	boardStatus *ui.Label
+	boardLines  []*ui.Label
+}
+
+// maxStatLines is the most lines the results panel lists: the stats every
+// run has plus the goal and score of the modes that have them, the grades
+// of the Music scheme, the cause of a failure and the credits earned.
+const maxStatLines = 16
+
+// overspeedWarning is the fraction of the airframe's speed limit past
+// which the HUD warns of the stress.
+const overspeedWarning = 0.9
+
+func newTheme() *ui.Theme {
+	theme := ui.DefaultTheme(myFont)
+	theme.DrawText = drawTextWithOutline
+	return theme
+}
+
+func newHUD(access accessibility) *hud {
+	h := &hud{}
+	theme := newTheme()
+	pal := access.palette()
+
+	h.status = &ui.Label{}
+	h.goal = &ui.Label{Face: smallFont}
+	h.forecast = &ui.Label{Face: smallFont}
+	h.warning = &ui.Label{Face: smallFont, Color: color.RGBA{255, 90, 70, 255}}
+	h.fuel = &ui.ProgressBar{
+		Box:         ui.Box{Width: 300, Height: 20},
+		Inset:       2,
+		Track:       pal.Track,
+		Fill:        pal.Fuel,
+		Border:      pal.Border,
+		BorderWidth: 2,
+		MarkColor:   pal.Danger,
+	}
+	h.comboLabel = &ui.Label{}
+	h.tpsLabel = &ui.Label{}
+	h.comboBar = &ui.ProgressBar{
+		Box:         ui.Box{Width: 224, Height: 20},
+		Inset:       2,
+		Track:       pal.Track,
+		Fill:        pal.Combo,
+		Border:      pal.Border,
+		BorderWidth: 2,
+	}
+	h.combo = &ui.Panel{
+		Align:   ui.AlignCenter,
+		Spacing: 4,
+		Children: []ui.Widget{
+			&ui.Panel{
+				Box:       ui.Box{Width: 300},
+				Direction: ui.Horizontal,
+				Justify:   ui.AlignCenter,
+				Spacing:   24,
+				Children:  []ui.Widget{h.comboLabel, h.tpsLabel},
+			},
+			h.comboBar,
+		},
+	}
+	h.zoneLabel = &ui.Label{}
+	h.entering = &ui.Label{Face: smallFont}
+	h.banner = &ui.Panel{
+		Padding:    10,
+		Align:      ui.AlignCenter,
+		Background: color.RGBA{0, 0, 0, 140},
+		Children:   []ui.Widget{h.entering, h.zoneLabel},
+	}
+	h.captionBox = &ui.Panel{
+		Padding:    6,
+		Spacing:    2,
+		Align:      ui.AlignCenter,
+		Background: color.RGBA{0, 0, 0, 200},
+	}
+	for i := 0; i < maxCaptions; i++ {
+		l := &ui.Label{Plain: true, Face: smallFont}
+		h.captions = append(h.captions, l)
+		h.captionBox.Children = append(h.captionBox.Children, l)
+	}
+	h.root = ui.NewRoot(theme, screenWidth, screenHeight, &ui.Overlay{
+		Children: []ui.Placement{
+			{Anchor: ui.TopCenter, Y: 12, Child: h.goal},
+			{Anchor: ui.TopCenter, Y: 50, Child: h.status},
+			{Anchor: ui.TopCenter, Y: 96, Child: h.forecast},
+			{Anchor: ui.TopCenter, Y: 96, Child: h.warning},
+			{Anchor: ui.TopCenter, Y: 110, Child: h.banner},
+			{Anchor: ui.TopCenter, Y: 460, Child: h.combo},
+			{Anchor: ui.TopCenter, Y: 560, Child: h.fuel},
+			{Anchor: ui.BottomCenter, Y: -8, Child: h.captionBox},
+		},
+	})
+
+	h.title = &ui.Label{}
+	h.hint = &ui.Label{}
+	stats := &ui.Panel{Spacing: 2}
+	for i := 0; i < maxStatLines; i++ {
+		l := &ui.Label{Plain: true, Face: smallFont}
+		h.statLines = append(h.statLines, l)
+		stats.Children = append(stats.Children, l)
+	}
+	h.boardTitle = &ui.Label{Face: smallFont}
+	h.boardStatus = &ui.Label{Plain: true, Face: smallFont}
+	h.board = &ui.Panel{Spacing: 2, Align: ui.AlignCenter, Children: []ui.Widget{h.boardTitle, h.boardStatus}}
+	for i := 0; i < boardRows; i++ {
+		l := &ui.Label{Plain: true, Face: smallFont}
+		h.boardLines = append(h.boardLines, l)
+		h.board.Children = append(h.board.Children, l)
+	}
+	h.results = ui.NewRoot(theme, screenWidth, screenHeight, &ui.Overlay{
+		Background: color.RGBA{0, 0, 0, 160},
+		Children: []ui.Placement{
+			{Anchor: ui.Center, Child: &ui.Panel{
+				Box:        ui.Box{Width: 400},
+				Padding:    24,
+				Spacing:    8,
+				Align:      ui.AlignCenter,
+				Background: color.RGBA{18, 22, 36, 230},
+				Children: []ui.Widget{
+					h.title,
+					h.hint,
+					&ui.Spacer{Box: ui.Box{Height: 4}},
+					stats,
+					h.board,
+				},
+			}},
+		},
+	})
+	return h
+}
+
+// sync copies the game state the HUD displays into its widgets.
+func (h *hud) sync(g *Game) {
+	f := g.flight
+	h.status.Hidden = false
+	switch {
+	case f.Launched:
+		h.status.Text = g.length(f.Altitude)
+	case f.Charging:
+		h.status.Text = g.lang.T("hud.charge")
+	default:
+		h.status.Hidden = true
+	}
+	h.goal.Hidden = f.Goal.Mode == sim.Classic && g.mission == nil
+	switch left := f.TapsLeft(); {
+	case left >= 0 && f.Charging:
+		h.goal.Text = g.lang.N("hud.taps_left", left, left)
+	case f.Goal.Mode != sim.Classic:
+		h.goal.Text = g.goalText(f.Goal)
+	case g.mission == nil:
+	case g.mission.ghost != nil:
+		h.goal.Text = g.lang.T("mission.ghost", g.length(g.mission.ghost.flight.Altitude))
+	default:
+		m := g.mission.mission
+		h.goal.Text = g.missionText(m, "name", m.Name)
+	}
+
+	// The forecast is up on the pad until launch.
+	h.forecast.Hidden = f.Launched || f.Over
+	h.forecast.Text = g.lang.T("hud.forecast", g.forecast(f.Weather))
+
+	// Once launched, the warning takes the forecast's place.
+	h.warning.Hidden = false
+	switch {
+	case f.StagePending():
+		h.warning.Text = g.lang.T("hud.stage", g.lang.Number(math.Max(0, f.Risks.StageWindow-f.StageWait), 1))
+	case f.Launched && f.Failure == sim.NoFailure && f.Risks.MaxSpeed > 0 && f.Speed > f.Risks.MaxSpeed*overspeedWarning:
+		h.warning.Text = g.lang.T("hud.overspeed", g.lang.Number(f.Speed, 1), g.lang.Number(f.Risks.MaxSpeed, 1))
+	default:
+		h.warning.Hidden = true
+	}
+
+	h.fuel.Hidden = !((f.Ready >= 3 && !f.PowerDown) || f.Launched) || f.PowerMax <= 0
+	h.fuel.Mark = f.Risks.RedLine
+	if f.PowerMax > 0 {
+		h.fuel.Value = f.Power / f.PowerMax
+		pct := g.lang.Number(math.Max(0, math.Min(1, h.fuel.Value))*100, 0)
+		h.fuel.Label = g.lang.T("hud.fuel", pct)
+		if f.Charging && !f.Launched && f.InRedZone() {
+			h.fuel.Label = g.lang.T("hud.fuel_red", pct, g.lang.Number(f.EngineRisk()*100, 0))
+		}
+	}
+
+	h.combo.Hidden = !(f.Charging && !f.Launched && f.ComboCount > 0)
+	h.comboBar.Value = f.ComboTimer / f.ComboWindow()
+	h.comboLabel.Text = g.lang.T("hud.combo", f.ComboCount)
+	h.tpsLabel.Hidden = f.PrepDuration <= 0
+	if f.PrepDuration > 0 {
+		h.tpsLabel.Text = g.lang.T("hud.tps", g.lang.Number(float64(f.TapCount)/f.PrepDuration, 1))
+	}
+
+	h.captionBox.Hidden = len(g.captions) == 0
+	for i, l := range h.captions {
+		l.Hidden = i >= len(g.captions)
+		if i < len(g.captions) {
+			l.Text = g.tr(g.captions[i].text)
+		}
+	}
+
+	h.banner.Hidden = g.zoneBanner <= 0
+	h.entering.Text = g.lang.T("hud.entering")
+	h.zoneLabel.Text = g.zoneName(sim.Zones[f.ZoneReached].Name)
+
+	r := g.lastResult
+	var lines []string
+	if g.mission != nil {
+		num := g.lang.Number
+		lines = append(g.missionLines(),
+			g.lang.T("stat.altitude", g.length(r.Altitude)),
+			g.lang.N("stat.taps", r.TapCount, num(float64(r.TapCount), 0), num(r.AverageTPS, 1)),
+		)
+		if r.Failure != sim.NoFailure {
+			lines = append(lines, g.lang.T("stat.failure", g.lang.T("failure."+string(r.Failure))))
+		}
+		h.title.Text = g.lang.T("mission.failed")
+		if g.mission.passed {
+			h.title.Text = g.lang.T("mission.complete")
+		}
+		h.hint.Text = g.lang.T("mission.hint")
+	} else {
+		h.title.Text = g.lang.T(resultTitle(r))
+		h.hint.Text = g.lang.T("hud.relaunch")
+		lines = g.runLines(r)
+	}
+	if g.credits > 0 {
+		lines = append(lines, g.lang.N("stat.credits", g.credits, g.credits, g.profile.Credits))
+	}
+	for i, l := range h.statLines {
+		l.Hidden = i >= len(lines)
+		if i < len(lines) {
+			l.Text = lines[i]
+		}
+	}
+
+	h.board.Hidden = g.board == nil
+	if g.board == nil {
+		return
+	}
+	h.boardTitle.Text = fmt.Sprintf("< %s >", g.tr(g.boardTab.title()))
+	h.boardStatus.Text = g.tr(g.boardView.status)
+	rows := g.boardLines()
+	for i, l := range h.boardLines {
+		l.Hidden = i >= len(rows)
+		if i < len(rows) {
+			l.Text = rows[i]
+		}
+	}
+}
+
+// runLines are the results panel's lines for a launch outside the
+// campaign.
+func (g *Game) runLines(r sim.ResultStats) []string {
+	goal := sim.GoalOf(r.CategoryOrNormal())
+	num := g.lang.Number
+	best := g.lang.T("stat.no_best")
+	if _, ok := g.highscores[r.CategoryOrNormal()]; ok {
+		best = g.formatScore(goal.Mode, g.saved_highscore)
+	}
+	var lines []string
+	if r.Failure != sim.NoFailure {
+		lines = append(lines, g.lang.T("stat.failure", g.lang.T("failure."+string(r.Failure))))
+	}
+	if goal.Mode != sim.Classic {
+		lines = append(lines, g.lang.T("stat.goal", g.goalName(goal)))
+	}
+	if goal.Mode.LowerIsBetter() {
+		lines = append(lines, g.lang.T("stat.score", g.formatScore(goal.Mode, r.OutcomeOrClassic().Score)))
+	}
+	// The goal has a line of its own, so the difficulty line leaves it out.
+	difficulty, _, _ := strings.Cut(r.CategoryOrNormal(), ":")
+	lines = append(lines,
+		g.lang.T("stat.difficulty", g.categoryName(difficulty)),
+		g.lang.T("stat.altitude", g.length(r.Altitude)),
+		g.lang.T("stat.zone", g.zoneName(r.HighestZone)),
+		g.lang.T("stat.weather", g.forecast(r.Weather)),
+		g.lang.T("stat.best", best),
+		g.lang.T("stat.flight_time", num(r.Duration, 1)),
+		g.lang.T("stat.prep_time", num(r.PrepDuration, 1)),
+		g.lang.T("stat.peak_speed", num(r.PeakSpeed, 1)),
+		g.lang.T("stat.fuel", num(r.FuelCollected, 0)),
+		g.lang.T("stat.combo", r.MaxCombo),
+		g.lang.N("stat.taps", r.TapCount, num(float64(r.TapCount), 0), num(r.AverageTPS, 1)),
+	)
+	if r.Perfects+r.Goods+r.Misses > 0 {
+		lines = append(lines, g.lang.T("stat.grades", r.Perfects, r.Goods, r.Misses))
+	}
+	return lines
+}
+
+// resultTitle is the catalog key heading the results panel for r.
+func resultTitle(r sim.ResultStats) string {
+	m, o := sim.GoalOf(r.CategoryOrNormal()).Mode, r.OutcomeOrClassic()
+	switch {
+	case r.Failure != sim.NoFailure:
+		return "result.failure." + string(r.Failure)
+	case m == sim.Target && o.Missed:
+		return "result.target_missed"
+	case m == sim.Target:
+		return "result.target_met"
+	case m == sim.Precision && o.Missed:
+		return "result.precision_missed"
+	case m == sim.Precision:
+		return "result.precision_met"
+	}
+	return "hud.results"
+}
@@ -0,0 +1,243 @@
+package leaderboard
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrQueued is returned by Submit when the server could not be reached and
+// the submission was queued for a later Flush.
+var ErrQueued = errors.New("leaderboard unreachable; submission queued")
+
+// RejectedError is a submission the server refused. Retrying it will not
+// help, so it is never queued.
+type RejectedError struct {
+	Status int
+	Rejection
+}
+
+func (e *RejectedError) Error() string {
+	msg := fmt.Sprintf("leaderboard rejected run (%d): %s", e.Status, e.Reason)
+	for _, m := range e.Mismatches {
+		msg += "; " + m
+	}
+	for _, f := range e.Flags {
+		msg += "; " + f
+	}
+	return msg
+}
+
+// Client talks to a leaderboard Server. Submissions that fail for want of
+// a connection are kept, on disk when QueuePath is set, and retried by
+// Flush.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	// QueuePath is where unsent submissions are kept between sessions.
+	// Empty keeps them in memory only.
+	QueuePath string
+
+	mu    sync.Mutex
+	queue []Submission
+	// sending is held while submissions are sent, so a retry and a new
+	// submission never send the same queued run or pop each other's.
+	sending sync.Mutex
+}
+
+// NewClient talks to the server at baseURL through hc, or
+// http.DefaultClient when hc is nil.
+func NewClient(baseURL string, hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: hc}
+}
+
+// LoadQueue sets QueuePath and reads any submissions left there by an
+// earlier session. A missing file is an empty queue.
+func (c *Client) LoadQueue(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.QueuePath = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var q []Submission
+	if err := json.Unmarshal(data, &q); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	c.queue = append(q, c.queue...)
+	return nil
+}
+
+// Pending reports how many submissions are waiting to be sent.
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
+
+// Submit sends sub after anything already queued. If the server cannot be
+// reached sub joins the queue and ErrQueued is returned.
+func (c *Client) Submit(ctx context.Context, sub Submission) (Standing, error) {
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	if _, err := c.flush(ctx); err != nil && !errors.Is(err, ErrQueued) {
+		return Standing{}, err
+	}
+	if c.Pending() > 0 {
+		return Standing{}, c.enqueue(sub)
+	}
+	st, err := c.send(ctx, sub)
+	if isTransient(err) {
+		return Standing{}, c.enqueue(sub)
+	}
+	return st, err
+}
+
+// Flush sends queued submissions in order until one fails to get through.
+// Rejected submissions are dropped. It returns how many were accepted.
+func (c *Client) Flush(ctx context.Context) (int, error) {
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	return c.flush(ctx)
+}
+
+// flush is Flush for a caller holding c.sending.
+func (c *Client) flush(ctx context.Context) (int, error) {
+	sent := 0
+	for {
+		c.mu.Lock()
+		if len(c.queue) == 0 {
+			c.mu.Unlock()
+			return sent, nil
+		}
+		sub := c.queue[0]
+		c.mu.Unlock()
+
+		_, err := c.send(ctx, sub)
+		if isTransient(err) {
+			return sent, ErrQueued
+		}
+		c.mu.Lock()
+		c.queue = c.queue[1:]
+		saveErr := c.saveLocked()
+		c.mu.Unlock()
+		if saveErr != nil {
+			return sent, saveErr
+		}
+		if err == nil {
+			sent++
+		}
+	}
+}
+
+func (c *Client) enqueue(sub Submission) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queue = append(c.queue, sub)
+	if err := c.saveLocked(); err != nil {
+		return err
+	}
+	return ErrQueued
+}
+
+func (c *Client) saveLocked() error {
+	if c.QueuePath == "" {
+		return nil
+	}
+	if len(c.queue) == 0 {
+		err := os.Remove(c.QueuePath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.Marshal(c.queue)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.QueuePath, data, 0o644)
+}
+
+func (c *Client) send(ctx context.Context, sub Submission) (Standing, error) {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return Standing{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/runs", bytes.NewReader(body))
+	if err != nil {
+		return Standing{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var st Standing
+	return st, c.do(req, &st)
+}
+
+// Top fetches the best n players in category.
+func (c *Client) Top(ctx context.Context, category string, n int) ([]Standing, error) {
+	return c.get(ctx, "/top", url.Values{"category": {category}, "n": {strconv.Itoa(n)}})
+}
+
+// Nearby fetches the standings in category within radius places of player.
+func (c *Client) Nearby(ctx context.Context, category, player string, radius int) ([]Standing, error) {
+	return c.get(ctx, "/nearby", url.Values{"category": {category}, "player": {player}, "n": {strconv.Itoa(radius)}})
+}
+
+// Friends fetches the standings in category of player and their friends.
+func (c *Client) Friends(ctx context.Context, category, player string, friends []string) ([]Standing, error) {
+	return c.get(ctx, "/friends", url.Values{"category": {category}, "player": {player}, "friends": {strings.Join(friends, ",")}})
+}
+
+func (c *Client) get(ctx context.Context, path string, q url.Values) ([]Standing, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var out []Standing
+	return out, c.do(req, &out)
+}
+
+// transientError marks failures worth retrying: no connection, or the
+// server erroring rather than refusing.
+type transientError struct{ err error }
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+func isTransient(err error) bool {
+	var t transientError
+	return errors.As(err, &t)
+}
+
+func (c *Client) do(req *http.Request, out any) error {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return transientError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return transientError{fmt.Errorf("leaderboard %s: %s", req.URL.Path, resp.Status)}
+	}
+	if resp.StatusCode >= 400 {
+		rej := &RejectedError{Status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&rej.Rejection); err != nil {
+			rej.Reason = resp.Status
+		}
+		return rej
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
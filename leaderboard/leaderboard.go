@@ -0,0 +1,140 @@
+// Package leaderboard is the shared high-score service: an HTTP server that
+// re-simulates every submitted replay before ranking it, the stores it can
+// keep entries in, and the client the game talks to it with.
+package leaderboard
+
+import (
+	"sort"
+	"time"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// Entry is one accepted run on the board. Its statistics come from the
+// server's own simulation of the replay, never from the submitter.
+type Entry struct {
+	ID          int64   `json:"id"`
+	Player      string  `json:"player"`
+	Altitude    float64 `json:"altitude"`
+	TapCount    int     `json:"taps"`
+	AverageTPS  float64 `json:"tps"`
+	MaxCombo    int     `json:"max_combo"`
+	HighestZone string  `json:"highest_zone"`
+	// Category is the difficulty and goal played; boards are kept per
+	// category.
+	Category string `json:"category"`
+	// Score and Missed are the run's sim.Outcome against its goal.
+	Score  float64 `json:"score,omitempty"`
+	Missed bool    `json:"missed,omitempty"`
+	// Weather and Wind are the sky the run was flown in; see sim.Weather.
+	Weather   string    `json:"weather,omitempty"`
+	Wind      float64   `json:"wind,omitempty"`
+	Submitted time.Time `json:"submitted"`
+}
+
+// Submission is what a client posts after a launch.
+type Submission struct {
+	Player string      `json:"player"`
+	Replay *sim.Replay `json:"replay"`
+}
+
+// Standing is a ranked entry. Rank starts at 1; it is 0 for an accepted
+// run that missed its goal, which is not ranked.
+type Standing struct {
+	Rank  int   `json:"rank"`
+	Entry Entry `json:"entry"`
+}
+
+// Store keeps accepted entries. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Add assigns e an ID and keeps it.
+	Add(e Entry) (Entry, error)
+	// Entries returns every entry in no particular order.
+	Entries() ([]Entry, error)
+	Close() error
+}
+
+// rank orders each player's best entry in category, best score first;
+// ties go to whoever got there first. Runs that missed their goal are not
+// ranked.
+func rank(entries []Entry, category string) []Standing {
+	mode := sim.GoalOf(category).Mode
+	best := make(map[string]Entry)
+	for _, e := range entries {
+		if e.category() != category || e.Missed {
+			continue
+		}
+		if b, ok := best[e.Player]; !ok || better(mode, e, b) {
+			best[e.Player] = e
+		}
+	}
+	out := make([]Standing, 0, len(best))
+	for _, e := range best {
+		out = append(out, Standing{Entry: e})
+	}
+	sort.Slice(out, func(i, j int) bool { return better(mode, out[i].Entry, out[j].Entry) })
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
+
+// category treats entries from before difficulties existed as Normal.
+func (e Entry) category() string {
+	if e.Category == "" {
+		return string(sim.Normal)
+	}
+	return e.Category
+}
+
+// Outcome is the entry's score against its goal. Classic entries are
+// scored on altitude, which older entries carry no separate score for.
+func (e Entry) Outcome() sim.Outcome {
+	if sim.GoalOf(e.category()).Mode == sim.Classic {
+		return sim.Outcome{Score: e.Altitude}
+	}
+	return sim.Outcome{Missed: e.Missed, Score: e.Score}
+}
+
+func better(mode sim.Mode, a, b Entry) bool {
+	if oa, ob := a.Outcome(), b.Outcome(); oa != ob {
+		return mode.Beats(oa, ob)
+	}
+	if !a.Submitted.Equal(b.Submitted) {
+		return a.Submitted.Before(b.Submitted)
+	}
+	return a.ID < b.ID
+}
+
+// Top returns the first n standings.
+func Top(standings []Standing, n int) []Standing {
+	return standings[:min(n, len(standings))]
+}
+
+// Nearby returns up to radius standings either side of player, including
+// the player's own. It is empty when the player has no entry.
+func Nearby(standings []Standing, player string, radius int) []Standing {
+	for i, s := range standings {
+		if s.Entry.Player == player {
+			return standings[max(0, i-radius):min(len(standings), i+radius+1)]
+		}
+	}
+	return nil
+}
+
+// Friends keeps the standings of player and everyone in friends, with their
+// global ranks.
+func Friends(standings []Standing, player string, friends []string) []Standing {
+	keep := map[string]bool{player: true}
+	for _, f := range friends {
+		keep[f] = true
+	}
+	var out []Standing
+	for _, s := range standings {
+		if keep[s.Entry.Player] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,362 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// masher alternates the charge buttons with uneven, human-paced gaps,
+// stops after taps presses, and stages as soon as the booster is spent.
+type masher struct {
+	taps int
+	next int
+	i    int
+	last sim.Button
+}
+
+func (m *masher) Presses(f *sim.Flight) []sim.Button {
+	if f.StagePending() {
+		return []sim.Button{sim.Stage}
+	}
+	if !f.CanCharge() || f.StepIndex < m.next || m.i >= m.taps {
+		return nil
+	}
+	gaps := []int{5, 7, 4, 6, 8, 5}
+	m.next = f.StepIndex + gaps[m.i%len(gaps)]
+	m.i++
+	if m.last == sim.Left {
+		m.last = sim.Right
+	} else {
+		m.last = sim.Left
+	}
+	return []sim.Button{m.last}
+}
+
+// flown records a Normal launch of taps presses in mode's standard goal.
+func flown(t *testing.T, seed uint64, mode sim.Mode, taps int) *sim.Replay {
+	t.Helper()
+	cfg := sim.Preset(sim.Normal)
+	cfg.Goal = sim.DefaultGoal(mode)
+	cfg.Weather = sim.RollWeather(seed)
+	return fly(t, seed, cfg, taps)
+}
+
+// fly records a launch of cfg and taps presses.
+func fly(t *testing.T, seed uint64, cfg sim.Config, taps int) *sim.Replay {
+	t.Helper()
+	r := sim.NewReplay(seed, cfg)
+	res, err := sim.Run(cfg, seed, &masher{taps: taps}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Result = &res
+	return r
+}
+
+// parts is a catalog whose stock parts make sim.StockRocket, with a
+// bigger tank to fit.
+var parts = &hangar.Catalog{Parts: []hangar.Part{
+	{ID: "hull", Slot: hangar.Body, Mass: 48},
+	{ID: "cone", Slot: hangar.Nose, Drag: 1},
+	{ID: "fins", Slot: hangar.Fins},
+	{ID: "engine", Slot: hangar.Engine, Thrust: 126},
+	{ID: "tank", Slot: hangar.Tank, Fuel: 1200},
+	{ID: "big-tank", Slot: hangar.Tank, Fuel: 1500, Mass: 2, Price: 10},
+	{ID: "white", Slot: hangar.Paint},
+	{ID: "flame", Slot: hangar.Exhaust},
+}}
+
+// board serves a fresh file store. While down is set it answers every
+// request with 503, as a server that is restarting would.
+type board struct {
+	store *FileStore
+	down  atomic.Bool
+	url   string
+}
+
+func newBoard(t *testing.T) *board {
+	t.Helper()
+	store, err := OpenFileStore(filepath.Join(t.TempDir(), "board.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &board{store: store}
+	srv := NewServer(store)
+	srv.Parts = parts
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.down.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	t.Cleanup(func() {
+		ts.Close()
+		store.Close()
+	})
+	b.url = ts.URL
+	return b
+}
+
+func (b *board) entries(t *testing.T) []Entry {
+	t.Helper()
+	entries, err := b.store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestSubmitAccepted(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	r := flown(t, 1, sim.Classic, 400)
+	st, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Rank != 1 || st.Entry.Altitude != r.Result.Altitude {
+		t.Errorf("standing = %+v, want rank 1 at %.0fm", st, r.Result.Altitude)
+	}
+	top, err := c.Top(context.Background(), r.Result.Category, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Entry.Player != "ada" {
+		t.Errorf("top = %+v, want ada alone", top)
+	}
+}
+
+func TestSubmitRejected(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	r := flown(t, 1, sim.Classic, 400)
+	r.Result.Altitude *= 2
+	_, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: r})
+	var rej *RejectedError
+	if !errors.As(err, &rej) || rej.Status != http.StatusUnprocessableEntity {
+		t.Fatalf("err = %v, want a 422 rejection", err)
+	}
+	if c.Pending() != 0 || len(b.entries(t)) != 0 {
+		t.Errorf("rejected run was queued or stored")
+	}
+}
+
+func TestQueueAndRetry(t *testing.T) {
+	b := newBoard(t)
+	queue := filepath.Join(t.TempDir(), "queue.json")
+	c := NewClient(b.url, nil)
+	if err := c.LoadQueue(queue); err != nil {
+		t.Fatal(err)
+	}
+
+	b.down.Store(true)
+	for seed := range uint64(2) {
+		_, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: flown(t, seed+1, sim.Classic, 400)})
+		if !errors.Is(err, ErrQueued) {
+			t.Fatalf("err = %v, want ErrQueued", err)
+		}
+	}
+
+	// The queue outlives the client.
+	c = NewClient(b.url, nil)
+	if err := c.LoadQueue(queue); err != nil {
+		t.Fatal(err)
+	}
+	if c.Pending() != 2 {
+		t.Fatalf("pending = %d, want 2", c.Pending())
+	}
+	if n, err := c.Flush(context.Background()); !errors.Is(err, ErrQueued) || n != 0 {
+		t.Fatalf("flush while down = %d, %v; want 0, ErrQueued", n, err)
+	}
+
+	b.down.Store(false)
+	if n, err := c.Flush(context.Background()); err != nil || n != 2 {
+		t.Fatalf("flush = %d, %v; want 2, nil", n, err)
+	}
+	if c.Pending() != 0 || len(b.entries(t)) != 2 {
+		t.Errorf("pending %d, stored %d; want 0 and 2", c.Pending(), len(b.entries(t)))
+	}
+}
+
+func TestConcurrentFlush(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	b.down.Store(true)
+	for seed := range uint64(4) {
+		c.Submit(context.Background(), Submission{Player: "ada", Replay: flown(t, seed+1, sim.Classic, 400)})
+	}
+	b.down.Store(false)
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Flush(context.Background())
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Submit(context.Background(), Submission{Player: "ada", Replay: flown(t, 5, sim.Classic, 400)})
+	}()
+	wg.Wait()
+
+	seen := make(map[float64]int)
+	for _, e := range b.entries(t) {
+		seen[e.Altitude]++
+	}
+	if c.Pending() != 0 || len(b.entries(t)) != 5 {
+		t.Errorf("pending %d, stored %d; want 0 and 5", c.Pending(), len(b.entries(t)))
+	}
+	for alt, n := range seen {
+		if n > 1 {
+			t.Errorf("run at %.0fm stored %d times", alt, n)
+		}
+	}
+}
+
+func TestMissedGoal(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	r := flown(t, 1, sim.Target, 20)
+	if !r.Result.OutcomeOrClassic().Missed {
+		t.Fatalf("a %d tap launch reached the target", r.Result.TapCount)
+	}
+	st, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: r})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Rank != 0 || !st.Entry.Missed {
+		t.Errorf("standing = %+v, want an unranked missed entry", st)
+	}
+	if n, err := c.Flush(context.Background()); err != nil || n != 0 {
+		t.Errorf("flush = %d, %v; want nothing left to send", n, err)
+	}
+	if len(b.entries(t)) != 1 {
+		t.Errorf("stored %d entries, want 1", len(b.entries(t)))
+	}
+}
+
+func TestMusicBeat(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	music := func(forge func(*sim.Beat)) *sim.Replay {
+		cfg := sim.Preset(sim.Normal)
+		cfg.Scheme = sim.Music
+		cfg.Beat = sim.DefaultBeat
+		cfg.Beat.Start = 1.5
+		cfg.Weather = sim.RollWeather(1)
+		forge(&cfg.Beat)
+		return fly(t, 1, cfg, 40)
+	}
+	tests := []struct {
+		name  string
+		forge func(*sim.Beat)
+		ok    bool
+	}{
+		{"shipped beat map", func(*sim.Beat) {}, true},
+		{"calibrated", func(b *sim.Beat) { b.Latency = -0.2 }, true},
+		{"forged tempo", func(b *sim.Beat) { b.BPM = 1200 }, false},
+		{"forged offset", func(b *sim.Beat) { b.Offset = 0.2 }, false},
+		{"latency past calibration", func(b *sim.Beat) { b.Latency = 0.4 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: music(tt.forge)})
+			var rej *RejectedError
+			if tt.ok && err != nil {
+				t.Errorf("err = %v, want the run accepted", err)
+			}
+			if !tt.ok && !errors.As(err, &rej) {
+				t.Errorf("err = %v, want a rejection", err)
+			}
+		})
+	}
+}
+
+func TestForgedWeather(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	tests := []struct {
+		name    string
+		weather sim.Weather
+		ok      bool
+	}{
+		{"the seed's", sim.RollWeather(1), true},
+		{"always stormy", sim.Storm.Roll(1), false},
+		{"always clear", sim.Weather{}, false},
+		{"out of range", sim.Weather{Kind: sim.Thermals, Wind: 45}, false},
+		{"another seed's", sim.RollWeather(2), false},
+		{"another seed's storm", sim.Storm.Roll(2), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := sim.Preset(sim.Normal)
+			cfg.Weather = tt.weather
+			_, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: fly(t, 1, cfg, 400)})
+			var rej *RejectedError
+			if tt.ok && err != nil {
+				t.Errorf("err = %v, want the run accepted", err)
+			}
+			if !tt.ok && !errors.As(err, &rej) {
+				t.Errorf("err = %v, want a rejection", err)
+			}
+		})
+	}
+}
+
+func TestRocketParts(t *testing.T) {
+	b := newBoard(t)
+	c := NewClient(b.url, nil)
+	bigTank := sim.Loadout{Tank: "big-tank"}
+	tests := []struct {
+		name    string
+		loadout sim.Loadout
+		tune    func(*sim.Config)
+		want    string // the category ranked under, or "" for a rejection
+	}{
+		{"stock", sim.Loadout{}, func(*sim.Config) {}, "normal"},
+		{"built from parts", bigTank, func(c *sim.Config) { *c = parts.Rocket(bigTank).Apply(*c) }, "normal+parts"},
+		{"parts claimed, not fitted", bigTank, func(*sim.Config) {}, ""},
+		{"rocket left out", bigTank, func(c *sim.Config) { *c = parts.Rocket(bigTank).Apply(*c); c.Rocket = sim.Rocket{} }, ""},
+		{"forged tank", bigTank, func(c *sim.Config) { *c = parts.Rocket(bigTank).Apply(*c); c.PowerMax = 1e9 }, ""},
+		{"custom tuning", sim.Loadout{}, func(c *sim.Config) { c.Gravity = -30 }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := sim.Preset(sim.Normal)
+			cfg.Weather = sim.RollWeather(1)
+			tt.tune(&cfg)
+			r := fly(t, 1, cfg, 400)
+			r.Loadout = tt.loadout
+			st, err := c.Submit(context.Background(), Submission{Player: "ada", Replay: r})
+			if tt.want == "" {
+				var rej *RejectedError
+				if !errors.As(err, &rej) {
+					t.Errorf("err = %v, want a rejection", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Entry.Category != tt.want {
+				t.Errorf("ranked under %q, want %q", st.Entry.Category, tt.want)
+			}
+			if r.Result.Category != tt.want {
+				t.Errorf("recorded under %q, ranked under %q", r.Result.Category, tt.want)
+			}
+		})
+	}
+}
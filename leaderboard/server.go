@@ -0,0 +1,274 @@
+package leaderboard
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const (
+	// maxSubmission bounds a posted replay; a ten minute run of frantic
+	// tapping is well under this.
+	maxSubmission = 1 << 20
+	maxPlayerName = 24
+	defaultCount  = 10
+	maxCount      = 100
+)
+
+// Server is the leaderboard HTTP API:
+//
+//	POST /runs                          submit a Submission, returns its Standing
+//	GET  /top?n=10                      the best n players
+//	GET  /nearby?player=p&n=5           n standings either side of p
+//	GET  /friends?player=p&friends=a,b  p and their friends
+//
+// Every submitted replay is re-simulated and must reproduce the result it
+// carries with plausible input before it is stored. Each difficulty has its
+// own board, chosen with a category parameter that defaults to normal; a
+// run's category comes from its replayed tuning and loadout, not from the
+// submitter.
+type Server struct {
+	Store  Store
+	Limits sim.Limits
+	// Beat is the beat map of the game's music, which Music scheme runs
+	// must have been graded against; it defaults to sim.DefaultBeat.
+	Beat sim.Beat
+	// Parts is the hangar's catalog, which runs on rockets built from
+	// parts are rebuilt from; without it they are refused.
+	Parts *hangar.Catalog
+	// Now stamps accepted entries; it defaults to time.Now.
+	Now func() time.Time
+
+	mux *http.ServeMux
+}
+
+// NewServer serves store with the default verification limits and beat.
+func NewServer(store Store) *Server {
+	s := &Server{Store: store, Limits: sim.DefaultLimits(), Beat: sim.DefaultBeat, Now: time.Now}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("POST /runs", s.submit)
+	s.mux.HandleFunc("GET /top", s.top)
+	s.mux.HandleFunc("GET /nearby", s.nearby)
+	s.mux.HandleFunc("GET /friends", s.friends)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Rejection is the body of a 422 response: why a submission was refused.
+type Rejection struct {
+	Reason     string   `json:"error"`
+	Mismatches []string `json:"mismatches,omitempty"`
+	Flags      []string `json:"flags,omitempty"`
+}
+
+func (s *Server) submit(w http.ResponseWriter, r *http.Request) {
+	var sub Submission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmission)).Decode(&sub); err != nil {
+		writeJSON(w, http.StatusBadRequest, Rejection{Reason: "malformed submission: " + err.Error()})
+		return
+	}
+	if err := validatePlayer(sub.Player); err != nil {
+		writeJSON(w, http.StatusBadRequest, Rejection{Reason: err.Error()})
+		return
+	}
+	if sub.Replay == nil || sub.Replay.Result == nil {
+		writeJSON(w, http.StatusBadRequest, Rejection{Reason: "submission needs a replay with its result"})
+		return
+	}
+	if err := sub.Replay.Check(); err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: err.Error()})
+		return
+	}
+	cat, err := s.rankedCategory(sub.Replay)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: err.Error()})
+		return
+	}
+	// Launches from before failures rank with their preset but could not
+	// fail; a live run on a preset always carries its risks.
+	if sub.Replay.Config.Risks == (sim.Risks{}) {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: "replay predates launch failures"})
+		return
+	}
+
+	// The weather is left out of the category, so it must be the one the
+	// seed rolls: a launch that always flies in one kind could pick clear
+	// skies or thermals and climb higher on the same board.
+	if err := sub.Replay.Config.Weather.CheckRolled(sub.Replay.Seed); err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: err.Error()})
+		return
+	}
+	if cfg := sub.Replay.Config; cfg.Scheme == sim.Music {
+		if err := cfg.Beat.CheckLaunch(s.Beat); err != nil {
+			writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: err.Error()})
+			return
+		}
+	}
+
+	rep, err := sim.Verify(sub.Replay, *sub.Replay.Result, s.Limits)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{Reason: err.Error()})
+		return
+	}
+	if !rep.OK() {
+		writeJSON(w, http.StatusUnprocessableEntity, Rejection{
+			Reason:     "replay failed verification",
+			Mismatches: rep.Mismatches,
+			Flags:      rep.Flags,
+		})
+		return
+	}
+
+	got := rep.Reproduced
+	outcome := got.OutcomeOrClassic()
+	e, err := s.Store.Add(Entry{
+		Player:      sub.Player,
+		Altitude:    got.Altitude,
+		TapCount:    got.TapCount,
+		AverageTPS:  got.AverageTPS,
+		MaxCombo:    got.MaxCombo,
+		HighestZone: got.HighestZone,
+		Category:    cat,
+		Score:       outcome.Score,
+		Missed:      outcome.Missed,
+		Weather:     string(got.Weather.Kind),
+		Wind:        got.Weather.Wind,
+		Submitted:   s.Now().UTC(),
+	})
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	// A run that missed its goal is kept but never ranked, so it has no
+	// standing to answer with.
+	if e.Missed {
+		writeJSON(w, http.StatusCreated, Standing{Entry: e})
+		return
+	}
+	standings, err := s.standings(e.Category)
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	// The new run may not be the player's best, in which case their
+	// standing is their earlier entry's.
+	for _, st := range standings {
+		if st.Entry.Player == e.Player {
+			writeJSON(w, http.StatusCreated, st)
+			return
+		}
+	}
+	s.fail(w, errors.New("accepted entry missing from standings"))
+}
+
+func (s *Server) top(w http.ResponseWriter, r *http.Request) {
+	standings, err := s.standings(category(r))
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Top(standings, count(r)))
+}
+
+func (s *Server) nearby(w http.ResponseWriter, r *http.Request) {
+	standings, err := s.standings(category(r))
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(Nearby(standings, r.URL.Query().Get("player"), count(r))))
+}
+
+func (s *Server) friends(w http.ResponseWriter, r *http.Request) {
+	standings, err := s.standings(category(r))
+	if err != nil {
+		s.fail(w, err)
+		return
+	}
+	q := r.URL.Query()
+	var friends []string
+	if f := q.Get("friends"); f != "" {
+		friends = strings.Split(f, ",")
+	}
+	writeJSON(w, http.StatusOK, nonNil(Friends(standings, q.Get("player"), friends)))
+}
+
+// rankedCategory is the board r's run goes on. Only tuning the server can
+// rebuild is ranked: a preset with the hangar's parts fitted as r's
+// loadout says, which goes on the preset's board, tagged +parts unless the
+// parts make the stock rocket. Any other tuning would pass verification
+// whatever it was, so it is refused. Without a catalog only the stock
+// rocket is ranked.
+func (s *Server) rankedCategory(r *sim.Replay) (string, error) {
+	rocket := sim.StockRocket
+	if s.Parts != nil {
+		rocket = s.Parts.Rocket(r.Loadout)
+	}
+	if err := rocket.Check(); err != nil {
+		return "", err
+	}
+	if r.Config.DifficultyOn(rocket) == sim.Custom {
+		return "", errors.New("custom tuning is not ranked; only presets, with or without hangar parts")
+	}
+	return r.Config.CategoryOn(rocket), nil
+}
+
+func (s *Server) standings(category string) ([]Standing, error) {
+	entries, err := s.Store.Entries()
+	if err != nil {
+		return nil, err
+	}
+	return rank(entries, category), nil
+}
+
+func (s *Server) fail(w http.ResponseWriter, err error) {
+	log.Println("leaderboard:", err)
+	writeJSON(w, http.StatusInternalServerError, Rejection{Reason: "internal error"})
+}
+
+func validatePlayer(name string) error {
+	if name == "" || len(name) > maxPlayerName || strings.ContainsAny(name, ",\n\r\t") {
+		return errors.New("player name must be 1-24 characters without commas or control characters")
+	}
+	return nil
+}
+
+// category reads the category query parameter, falling back to normal.
+func category(r *http.Request) string {
+	if c := r.URL.Query().Get("category"); c != "" {
+		return c
+	}
+	return string(sim.Normal)
+}
+
+// count reads the n query parameter, falling back to defaultCount.
+func count(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		return defaultCount
+	}
+	return min(n, maxCount)
+}
+
+func nonNil(s []Standing) []Standing {
+	if s == nil {
+		return []Standing{}
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,146 @@
+package leaderboard
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileStore keeps entries as JSON lines, appending one per accepted run,
+// and serves reads from memory.
+type FileStore struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries []Entry
+}
+
+// OpenFileStore loads path, creating it if needed.
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileStore{f: f}
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		s.entries = append(s.entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Add(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.ID = int64(len(s.entries)) + 1
+	data, err := json.Marshal(e)
+	if err != nil {
+		return Entry{}, err
+	}
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		return Entry{}, err
+	}
+	s.entries = append(s.entries, e)
+	return e, nil
+}
+
+func (s *FileStore) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+func (s *FileStore) Close() error {
+	return s.f.Close()
+}
+
+// SQLStore keeps entries in a database/sql table. It is written against
+// SQLite but uses only portable SQL; the caller opens the database with
+// whichever driver the binary links in.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// OpenSQLStore creates the entries table in db if it is missing.
+func OpenSQLStore(db *sql.DB) (*SQLStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS entries (
+		id INTEGER PRIMARY KEY,
+		player TEXT NOT NULL,
+		altitude REAL NOT NULL,
+		taps INTEGER NOT NULL,
+		tps REAL NOT NULL,
+		max_combo INTEGER NOT NULL,
+		highest_zone TEXT NOT NULL,
+		category TEXT NOT NULL DEFAULT 'normal',
+		score REAL NOT NULL DEFAULT 0,
+		missed INTEGER NOT NULL DEFAULT 0,
+		weather TEXT NOT NULL DEFAULT '',
+		wind REAL NOT NULL DEFAULT 0,
+		submitted TEXT NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	// Tables made before per-difficulty boards, game modes and weather
+	// lack these columns; on newer ones each fails harmlessly as a
+	// duplicate.
+	db.Exec(`ALTER TABLE entries ADD COLUMN category TEXT NOT NULL DEFAULT 'normal'`)
+	db.Exec(`ALTER TABLE entries ADD COLUMN score REAL NOT NULL DEFAULT 0`)
+	db.Exec(`ALTER TABLE entries ADD COLUMN missed INTEGER NOT NULL DEFAULT 0`)
+	db.Exec(`ALTER TABLE entries ADD COLUMN weather TEXT NOT NULL DEFAULT ''`)
+	db.Exec(`ALTER TABLE entries ADD COLUMN wind REAL NOT NULL DEFAULT 0`)
+	return &SQLStore{db: db}, nil
+}
+
+func (s *SQLStore) Add(e Entry) (Entry, error) {
+	res, err := s.db.Exec(`INSERT INTO entries (player, altitude, taps, tps, max_combo, highest_zone, category, score, missed, weather, wind, submitted)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Player, e.Altitude, e.TapCount, e.AverageTPS, e.MaxCombo, e.HighestZone, e.Category, e.Score, e.Missed, e.Weather, e.Wind, e.Submitted.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return Entry{}, err
+	}
+	if e.ID, err = res.LastInsertId(); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+func (s *SQLStore) Entries() ([]Entry, error) {
+	rows, err := s.db.Query(`SELECT id, player, altitude, taps, tps, max_combo, highest_zone, category, score, missed, weather, wind, submitted FROM entries`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var submitted string
+		if err := rows.Scan(&e.ID, &e.Player, &e.Altitude, &e.TapCount, &e.AverageTPS, &e.MaxCombo, &e.HighestZone, &e.Category, &e.Score, &e.Missed, &e.Weather, &e.Wind, &submitted); err != nil {
+			return nil, err
+		}
+		if e.Submitted, err = time.Parse(time.RFC3339Nano, submitted); err != nil {
+			return nil, fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,167 @@
+// Package locale loads the message catalogs the game's on-screen text is
+// drawn from and formats numbers, plurals and lengths for each language.
+package locale
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Catalog is one language's messages, read from a JSON file named after
+// its tag, such as en.json.
+type Catalog struct {
+	Tag string `json:"-"`
+	// Name is the language's name in that language, for the switcher.
+	Name string `json:"name"`
+	// SpriteText is set when the text baked into the Ready, Set, Go
+	// sprites reads correctly in this language.
+	SpriteText bool `json:"sprite_text"`
+	// Decimal and Group are the decimal and thousands separators.
+	Decimal string `json:"decimal"`
+	Group   string `json:"group"`
+	// Fonts lists fonts to fall back on for scripts the game font lacks.
+	Fonts    []string           `json:"fonts"`
+	Messages map[string]Message `json:"messages"`
+
+	fallback *Catalog
+}
+
+// Message is a format string for fmt, or one per plural form for messages
+// that count something. A plain JSON string is stored as the Other form.
+type Message map[Form]string
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = Message{Other: s}
+		return nil
+	}
+	var forms map[Form]string
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	if _, ok := forms[Other]; !ok {
+		return fmt.Errorf("plural message has no %q form", Other)
+	}
+	*m = forms
+	return nil
+}
+
+// Load reads a single catalog. The tag is the file name without extension.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{Decimal: ".", Group: ","}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	c.Tag = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if c.Name == "" {
+		c.Name = c.Tag
+	}
+	return c, nil
+}
+
+// LoadDir reads every catalog in dir, sorted by tag. Messages missing from
+// a catalog are taken from the one tagged fallback, which must exist.
+func LoadDir(dir, fallback string) ([]*Catalog, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var catalogs []*Catalog
+	var base *Catalog
+	for _, path := range paths {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if c.Tag == fallback {
+			base = c
+		}
+		catalogs = append(catalogs, c)
+	}
+	if base == nil {
+		return nil, fmt.Errorf("no %s catalog in %s", fallback, dir)
+	}
+	for _, c := range catalogs {
+		if c != base {
+			c.fallback = base
+		}
+	}
+	sort.Slice(catalogs, func(i, j int) bool { return catalogs[i].Tag < catalogs[j].Tag })
+	return catalogs, nil
+}
+
+// Find returns the catalog tagged tag, or nil.
+func Find(catalogs []*Catalog, tag string) *Catalog {
+	for _, c := range catalogs {
+		if strings.EqualFold(c.Tag, tag) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) lookup(key string) (*Catalog, Message) {
+	for ; c != nil; c = c.fallback {
+		if m, ok := c.Messages[key]; ok {
+			return c, m
+		}
+	}
+	return nil, nil
+}
+
+// T formats the message key with args. A key no catalog has is returned
+// as is, so gaps show up on screen rather than as blank space.
+func (c *Catalog) T(key string, args ...any) string {
+	_, m := c.lookup(key)
+	if m == nil {
+		return key
+	}
+	return fmt.Sprintf(m[Other], args...)
+}
+
+// N formats the form of the message key that matches the count n, in the
+// plural rules of whichever catalog supplied the message.
+func (c *Catalog) N(key string, n int, args ...any) string {
+	owner, m := c.lookup(key)
+	if m == nil {
+		return key
+	}
+	format, ok := m[PluralForm(owner.Tag, n)]
+	if !ok {
+		format = m[Other]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Number formats v with the given number of decimals and the catalog's
+// separators.
+func (c *Catalog) Number(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, d := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(c.Group)
+		}
+		b.WriteRune(d)
+	}
+	if frac != "" {
+		b.WriteString(c.Decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
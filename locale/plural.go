@@ -0,0 +1,52 @@
+package locale
+
+import "strings"
+
+// Form is a CLDR plural category.
+type Form string
+
+const (
+	One   Form = "one"
+	Few   Form = "few"
+	Many  Form = "many"
+	Other Form = "other"
+)
+
+// PluralForm returns the plural category of the count n in the language
+// tagged tag. Only integer counts are needed, so the rules below are the
+// CLDR integer rules for the languages the game ships or is likely to.
+func PluralForm(tag string, n int) Form {
+	lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	if n < 0 {
+		n = -n
+	}
+	switch lang {
+	case "ja", "ko", "zh", "th", "vi", "id":
+		return Other
+	case "fr", "pt":
+		if n <= 1 {
+			return One
+		}
+	case "ru", "uk", "be":
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		}
+		return Many
+	case "pl":
+		switch {
+		case n == 1:
+			return One
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return Few
+		}
+		return Many
+	default:
+		if n == 1 {
+			return One
+		}
+	}
+	return Other
+}
@@ -0,0 +1,70 @@
+package locale
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Units is the measurement system lengths and speeds are shown in.
+type Units int
+
+const (
+	Metric Units = iota
+	Imperial
+)
+
+const feetPerMetre = 1 / 0.3048
+
+func (u Units) String() string {
+	if u == Imperial {
+		return "imperial"
+	}
+	return "metric"
+}
+
+// ParseUnits accepts "metric" or "imperial" in any case.
+func ParseUnits(name string) (Units, error) {
+	switch strings.ToLower(name) {
+	case "metric", "":
+		return Metric, nil
+	case "imperial":
+		return Imperial, nil
+	}
+	return Metric, fmt.Errorf("unknown units %q, want metric or imperial", name)
+}
+
+// Length converts metres into u.
+func (u Units) Length(metres float64) float64 {
+	if u == Imperial {
+		return metres * feetPerMetre
+	}
+	return metres
+}
+
+// Speed converts metres a second into u's speed unit: km/h, or mph.
+func (u Units) Speed(metresPerSecond float64) float64 {
+	if u == Imperial {
+		return metresPerSecond * 3600 / 1609.344
+	}
+	return metresPerSecond * 3.6
+}
+
+// Speed formats a speed given in metres a second, rounded to whole units,
+// using the catalog's "unit.kmh" or "unit.mph" message.
+func (c *Catalog) Speed(metresPerSecond float64, u Units) string {
+	key := "unit.kmh"
+	if u == Imperial {
+		key = "unit.mph"
+	}
+	return c.T(key, c.Number(u.Speed(metresPerSecond), 0))
+}
+
+// Length formats a length given in metres, rounded to whole units, using
+// the catalog's "unit.m" or "unit.ft" message.
+func (c *Catalog) Length(metres float64, u Units) string {
+	key := "unit.m"
+	if u == Imperial {
+		key = "unit.ft"
+	}
+	return c.T(key, c.Number(u.Length(metres), 0))
+}
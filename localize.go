@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"gitlab.com/Goodgis/go-game/locale"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+const (
+	langDir     = "assets/lang"
+	defaultLang = "en"
+)
+
+// languages are the catalogs whose fonts could all be loaded, in tag order.
+var languages []*locale.Catalog
+
+// loadLanguages reads the message catalogs and adds each one's fallback
+// fonts to the font library. A language whose fonts are missing is left
+// out rather than drawn as blank glyphs. It must run after loadFont.
+func loadLanguages() {
+	catalogs, err := locale.LoadDir(langDir, defaultLang)
+	if err != nil {
+		log.Fatal(err)
+	}
+	added := false
+	for _, c := range catalogs {
+		ok := true
+		for _, path := range c.Fonts {
+			if err := fontLib.AddFallback(path); err != nil {
+				log.Printf("language %s unavailable: %v", c.Tag, err)
+				ok = false
+				break
+			}
+			added = true
+		}
+		if ok {
+			languages = append(languages, c)
+		}
+	}
+	if added {
+		loadFaces(1)
+	}
+}
+
+// message is a catalog key and its arguments, kept unformatted until it is
+// drawn so that it follows the language in use at the time.
+type message struct {
+	key    string
+	args   []any
+	n      int
+	plural bool
+}
+
+func msg(key string, args ...any) message {
+	return message{key: key, args: args}
+}
+
+// countMsg is a message whose wording depends on the count n.
+func countMsg(key string, n int, args ...any) message {
+	return message{key: key, args: args, n: n, plural: true}
+}
+
+func (g *Game) tr(m message) string {
+	if m.key == "" {
+		return ""
+	}
+	if m.plural {
+		return g.lang.N(m.key, m.n, m.args...)
+	}
+	return g.lang.T(m.key, m.args...)
+}
+
+// length formats an altitude in the chosen units.
+func (g *Game) length(metres float64) string {
+	return g.lang.Length(metres, g.units)
+}
+
+// zoneName translates the name of one of sim.Zones.
+func (g *Game) zoneName(name string) string {
+	return g.lang.T("zone." + strings.ReplaceAll(strings.ToLower(name), " ", "_"))
+}
+
+// categoryName is categoryTitle in the player's language.
+func (g *Game) categoryName(cat string) string {
+	cat, key, _ := strings.Cut(cat, ":")
+	cat, scheme, _ := strings.Cut(cat, "/")
+	name, assisted := strings.CutSuffix(cat, "+assist")
+	title := g.lang.T("difficulty." + name)
+	if assisted {
+		title = g.lang.T("category.assisted", title)
+	}
+	if scheme != "" {
+		title = g.lang.T("category.scheme", title, g.lang.T("scheme."+scheme))
+	}
+	if goal, err := sim.ParseGoalKey(key); err == nil && goal.Mode != sim.Classic {
+		title = g.lang.T("category.goal", title, g.goalName(goal))
+	}
+	return title
+}
+
+// goalName is a goal's short name, such as "Target 5,000 m".
+func (g *Game) goalName(goal sim.Goal) string {
+	switch goal.Mode {
+	case sim.Target:
+		return g.lang.T("goal.target", g.length(goal.Altitude))
+	case sim.Precision:
+		return g.lang.T("goal.precision", g.length(goal.Altitude), g.length(goal.Tolerance))
+	case sim.Budget:
+		return g.lang.N("goal.budget", goal.Taps, goal.Taps)
+	}
+	return g.lang.T("mode.classic")
+}
+
+// goalText explains what the goal asks of the player.
+func (g *Game) goalText(goal sim.Goal) string {
+	switch goal.Mode {
+	case sim.Target:
+		return g.lang.T("goal.target_text", g.length(goal.Altitude))
+	case sim.Precision:
+		return g.lang.T("goal.precision_text", g.length(goal.Altitude-goal.Tolerance), g.length(goal.Altitude+goal.Tolerance))
+	case sim.Budget:
+		return g.lang.N("goal.budget_text", goal.Taps, goal.Taps)
+	}
+	return g.lang.T("goal.classic_text")
+}
+
+// formatScore shows a score in the units of mode m: taps for Target, the
+// distance from the middle of the band for Precision and the altitude
+// otherwise.
+func (g *Game) formatScore(m sim.Mode, score float64) string {
+	switch m {
+	case sim.Target:
+		n := int(score)
+		return g.lang.N("score.taps", n, n)
+	case sim.Precision:
+		return g.lang.T("score.off", g.length(score))
+	}
+	return g.length(score)
+}
+
+// setLanguage switches every on-screen string to the catalog tagged tag.
+func (g *Game) setLanguage(tag string) error {
+	c := locale.Find(languages, tag)
+	if c == nil {
+		var tags []string
+		for _, l := range languages {
+			tags = append(tags, l.Tag)
+		}
+		return fmt.Errorf("unknown language %q, want one of %v", tag, tags)
+	}
+	g.lang = c
+	return nil
+}
+
+// cycleLanguage moves to the next available language.
+func (g *Game) cycleLanguage() {
+	for i, c := range languages {
+		if c == g.lang {
+			g.lang = languages[(i+1)%len(languages)]
+			return
+		}
+	}
+}
+
+// drawReady draws a Ready, Set or Go word as text, for the languages the
+// sprite sheet was not drawn in. Words too wide for the big face drop to
+// the regular one.
+func (g *Game) drawReady(dst *ebiten.Image, word string, shakeX, shakeY float64) {
+	face := bigFont
+	if text.BoundString(face, word).Dx() > screenWidth-40 {
+		face = myFont
+	}
+	w := text.BoundString(face, word).Dx()
+	x := (screenWidth-w)/2 + int(shakeX)
+	y := 130 + 118*2/3 + int(shakeY)
+	drawTextWithOutline(dst, word, face, x, y, color.White, color.Black)
+}
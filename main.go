@@ -13,27 +13,33 @@ import (
 	"math"
 	"math/rand/v2"
 	"os"
+	"time"
 
 	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
 	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"gitlab.com/Goodgis/go-game/bot"
+	"gitlab.com/Goodgis/go-game/campaign"
+	"gitlab.com/Goodgis/go-game/fonts"
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/locale"
+	"gitlab.com/Goodgis/go-game/particles"
+	"gitlab.com/Goodgis/go-game/sim"
 )
 
 const (
 	sampleRate    = 44000
 	highscoreFile = "highscore.json"
-	comboTimeout  = 0.35
-	basePowerGain = 5.0
-	comboBonus    = 1.5
 )
 
 var (
 	bg           *ebiten.Image
+	bgSource     image.Image
 	player       *ebiten.Image
 	countdown    *ebiten.Image
 	ready_set_go *ebiten.Image
@@ -42,11 +48,15 @@ var (
 	zbutton      *ebiten.Image
 	xbutton      *ebiten.Image
 	smoke        *ebiten.Image
-	myFont       font.Face
-	screenWidth  = 480
-	screenHeight = 640
-	rsg          = 0
-	count        = 10
+
+	particleDefs    map[string]*particles.EmitterDef
+	particleSprites map[string]*ebiten.Image
+	bigFont         font.Face
+	myFont          font.Face
+	smallFont       font.Face
+	fontLib         *fonts.Library
+	screenWidth     = 480
+	screenHeight    = 640
 
 	audioContext *audio.Context
 	musicPlayer  *audio.Player
@@ -61,31 +71,35 @@ var (
 )
 
 type Game struct {
-	altitude        float64
-	speed           float64
-	speedMax        float64
-	power           float64
-	powerMax        float64
-	gravity         float64
-	highscore       float64
+	flight *sim.Flight
+	// highscores holds the best altitude per difficulty category;
+	// saved_highscore is the one for the category being played.
+	highscores      map[string]float64
 	saved_highscore float64
-	peakSpeed       float64
-	totalFuel       float64
-	prepDuration    float64
-	runDuration     float64
-	comboTimer      float64
-	comboCount      int
-	maxCombo        int
-	tapCount        int
-	lastComboKey    ebiten.Key
-	gameOver        bool
 
 	bgoffset float64
+	skyTime  float64
+	camera   Camera
+	layers   []layer
+
+	zoneBanner float64
+	// grade is the last Music press's, shown for gradeFlash seconds.
+	grade      sim.Grade
+	gradeFlash float64
+	// weather is the weather setting, randomWeather or a kind, that each
+	// launch's sky is rolled from.
+	weather string
+	// latency is how late the player's presses arrive, in seconds, taken
+	// off before Music presses are graded.
+	latency float64
+
+	access   accessibility
+	captions []caption
 
-	prevKeys    map[ebiten.Key]bool
-	launched    bool
-	start_count bool
-	power_down  bool
+	lang  *locale.Catalog
+	units locale.Units
+
+	prevKeys map[ebiten.Key]bool
 
 	z_down int
 	x_down int
@@ -93,36 +107,74 @@ type Game struct {
 	launchSFXPlayer *audio.Player
 	voicePlayer     *audio.Player
 
-	rsg_timer   float64
-	count_timer float64
-
 	shakeTimer     float64
 	shakeDuration  float64
 	shakeMagnitude float64
 	shakeOffsetX   float64
 	shakeOffsetY   float64
 
-	particles []Particle
-
-	lastResult ResultStats
-}
-
-type Particle struct {
-	X, Y     float64
-	Radius   float64
-	Velocity float64
-	Opacity  float64
-}
-
-type ResultStats struct {
-	Altitude      float64
-	PeakSpeed     float64
-	Duration      float64
-	PrepDuration  float64
-	TapCount      int
-	MaxCombo      int
-	AverageTPS    float64
-	FuelCollected float64
+	fx      *particles.System
+	exhaust *particles.Emitter
+	smoke   *particles.Emitter
+	steam   *particles.Emitter
+
+	lastResult sim.ResultStats
+
+	clock        Clock
+	prevAltitude float64
+	keys         keyboardInput
+	input        sim.Input
+	// scheme handles one-button charging; nil when Z and X alternate.
+	scheme chargeScheme
+
+	// seed is the session seed from the command line; runSeed is the one
+	// the current launch uses, fixed by seed when set or drawn fresh.
+	seed    uint64
+	runSeed uint64
+	rng     *rand.Rand
+
+	persist   bool
+	recording *sim.Replay
+	playback  *sim.Playback
+	bot       bot.Agent
+
+	board     *boardLink
+	boardView boardView
+	boardTab  boardTab
+
+	// scene is the title menu or a flight; attract is set while the menu's
+	// demo replay plays.
+	scene       scene
+	title       *titleScreen
+	attract     bool
+	attractLeft float64
+
+	// mission is the campaign mission being flown, if any; progress is
+	// the campaign so far.
+	mission  *missionRun
+	progress campaign.Progress
+
+	// profile is the player's hangar, saved at profilePath; fitted is the
+	// loadout they fly and rocket the sprite drawn for this launch.
+	// credits is what the last launch earned.
+	profile     hangar.Profile
+	profilePath string
+	fitted      sim.Loadout
+	rocket      *ebiten.Image
+	credits     int
+
+	// capture takes screenshots and highlights; notice says where the
+	// last one went, until noticeUntil.
+	capture     *recorder
+	notice      message
+	noticeUntil time.Time
+
+	// debug is the overlay and console, with -debug; tampered is set once
+	// the console has changed the launch, which is then not kept.
+	debug    *debugTools
+	tampered bool
+
+	hud *hud
 }
 
 type pcmStream struct {
@@ -192,25 +244,35 @@ func loadVoiceSamples() {
 }
 
 func playVoiceClip(number int) *audio.Player {
-	if audioContext == nil {
-		return nil
-	}
 	clip, ok := voiceSamples[number]
 	if !ok {
 		return nil
 	}
+	if mixdown != nil {
+		mixdown.play(clip)
+		return nil
+	}
+	if audioContext == nil {
+		return nil
+	}
 	player, err := audio.NewPlayer(audioContext, newPCMStream(clip))
 	if err != nil {
 		log.Println("error playing voice clip:", err)
 		return nil
 	}
 	player.Play()
-	return player
+	return trackPlayer(player)
 }
 
-func init() {
+// loadAssets reads the font, sounds, artwork and effect definitions the
+// windowed game needs.
+func loadAssets() {
 	var err error
-	myFont = loadFont()
+	loadFont()
+	loadLanguages()
+	loadMissions()
+	loadHangar()
+	loadBeatMap()
 	loadVoiceSamples()
 
 	// Import Sounds
@@ -240,12 +302,12 @@ func init() {
 	}
 
 	// Load in Artwork
-	bg, _, err = ebitenutil.NewImageFromFile("assets/background.png")
+	bg, bgSource, err = ebitenutil.NewImageFromFile("assets/background.png")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	player, _, err = ebitenutil.NewImageFromFile("assets/player.png")
+	player, playerSource, err = ebitenutil.NewImageFromFile("assets/player.png")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -285,9 +347,21 @@ func init() {
 		log.Fatal(err)
 	}
 
+	particleDefs, err = particles.LoadDefs("assets/particles.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 }
 
 func playSFX(data []byte) *audio.Player {
+	if mixdown != nil {
+		mixdown.playMP3(data)
+		return nil
+	}
+	if audioContext == nil {
+		return nil
+	}
 	stream, err := mp3.Decode(audioContext, bytes.NewReader(data))
 	if err != nil {
 		log.Println("Error decoding sound:", err)
@@ -301,14 +375,14 @@ func playSFX(data []byte) *audio.Player {
 	}
 
 	sfxPlayer.Play()
-	return sfxPlayer
+	return trackPlayer(sfxPlayer)
 }
 
 func loadMusic() {
 	var err error
 	audioContext = audio.NewContext(sampleRate)
 
-	data, err := os.ReadFile("assets/sounds/bossa_nova.mp3")
+	data, err := os.ReadFile(musicTrack)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -318,7 +392,9 @@ func loadMusic() {
 		log.Fatal(err)
 	}
 
-	musicPlayer, err = audio.NewPlayer(audioContext, stream)
+	// The music loops, and the Music scheme's beats start again with it.
+	musicBeat.Length = float64(stream.Length()) / float64(4*stream.SampleRate())
+	musicPlayer, err = audio.NewPlayer(audioContext, audio.NewInfiniteLoop(stream, stream.Length()))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -326,45 +402,22 @@ func loadMusic() {
 	musicPlayer.Play()
 }
 
-func loadFont() font.Face {
-	ttfBytes, err := os.ReadFile("assets/font.ttf")
-	if err != nil {
-		log.Fatal(err)
-	}
-	tt, err := opentype.Parse(ttfBytes)
-	if err != nil {
-		log.Fatal(err)
-	}
-	const dpi = 72
-	face, err := opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    36,
-		DPI:     dpi,
-		Hinting: font.HintingFull,
-	})
+func loadFont() {
+	var err error
+	fontLib, err = fonts.Load("assets/font.ttf")
 	if err != nil {
 		log.Fatal(err)
 	}
-	return face
+	loadFaces(1)
 }
 
 func (g *Game) restartGame() {
-	g.altitude = -5763
-	g.speed = 0
-	g.power = 0
-	g.launched = false
-	g.start_count = false
-	g.power_down = false
-	g.gameOver = false
-	g.highscore = 0
-	g.totalFuel = 0
-	g.tapCount = 0
-	g.maxCombo = 0
-	g.comboCount = 0
-	g.comboTimer = 0
-	g.lastComboKey = 0
-	g.prepDuration = 0
-	g.runDuration = 0
-	g.peakSpeed = 0
+	g.flight.Reset()
+	g.prevAltitude = 0
+	g.tampered = false
+	if g.capture != nil {
+		g.capture.reset()
+	}
 	g.shakeTimer = 0
 	g.shakeOffsetX = 0
 	g.shakeOffsetY = 0
@@ -379,27 +432,66 @@ func (g *Game) restartGame() {
 	for k := range g.prevKeys {
 		g.prevKeys[k] = false
 	}
-	rsg = 0
-	count = 10
+	g.camera.Snap(rocketBaseY)
+	g.clock.Reset()
+	g.keys.pending = g.keys.pending[:0]
+	g.keys.held = false
+	g.keys.last = sim.NoButton
+	g.keys.nextAuto = 0
+	g.keys.autoTap = g.flight.Assists.AutoTap
+	if g.scheme != nil {
+		g.scheme.reset()
+	}
+	if g.fx != nil {
+		g.fx.Clear()
+	}
+	g.zoneBanner = 0
+	g.gradeFlash = 0
+	g.captions = nil
+	g.runSeed = g.seed
+	if g.playback != nil {
+		g.playback.Rewind()
+		g.runSeed = g.playback.Replay.Seed
+	} else if g.runSeed == 0 {
+		g.runSeed = rand.Uint64()
+	}
+	g.flight.Seed = g.runSeed
+	g.rng = rand.New(rand.NewPCG(g.runSeed, 0))
+	// Replays and missions keep the weather they were set up with.
+	if g.playback == nil && g.mission == nil {
+		g.flight.Weather = rollWeather(g.weather, g.runSeed)
+	}
+	g.input = &g.keys
+	switch {
+	case g.playback != nil:
+		g.input = g.playback
+	case g.bot != nil:
+		g.bot.Reset(g.runSeed)
+		g.input = g.bot
+	}
+	if g.mission != nil && g.mission.ghost != nil {
+		g.mission.ghost.reset()
+	}
+	if g.flight.Scheme == sim.Music && g.playback == nil {
+		g.flight.Beat = g.launchBeat()
+	}
+	if g.fx != nil {
+		g.applyLoadout()
+	}
+	g.recording = nil
+	if g.persist && g.playback == nil {
+		g.recording = sim.NewReplay(g.runSeed, g.flight.Config)
+		g.recording.Loadout = g.fitted
+	}
 }
 
 func drawTextWithOutline(dst *ebiten.Image, str string, face font.Face, x, y int, textColor, outlineColor color.Color) {
-	thickness := 6
-
-	for dx := -thickness; dx <= thickness; dx++ {
-		for dy := -thickness; dy <= thickness; dy++ {
-			if dx == 0 && dy == 0 {
-				continue
-			}
+	const thickness = 6
 
-			dist := math.Sqrt(float64(dx*dx + dy*dy))
-			if dist <= float64(thickness) {
-				text.Draw(dst, str, face, x+dx, y+dy, outlineColor)
-			}
-		}
+	if atlas := fontLib.AtlasFor(face, thickness); atlas != nil {
+		atlas.Draw(dst, str, x, y, textColor, outlineColor)
+		return
 	}
-
-	// Draw main text
 	text.Draw(dst, str, face, x, y, textColor)
 }
 
@@ -416,11 +508,10 @@ func drawCircle(dst *ebiten.Image, x, y, r float64, clr color.Color) {
 	dst.DrawImage(smoke, op)
 }
 
-func (g *Game) currentAltitude() float64 {
-	return ((g.altitude * -1) - 5763) * -1
-}
-
 func (g *Game) startScreenShake(duration, magnitude float64) {
+	if g.access.ReducedMotion {
+		return
+	}
 	g.shakeDuration = duration
 	g.shakeTimer = duration
 	g.shakeMagnitude = magnitude
@@ -438,117 +529,183 @@ func (g *Game) updateScreenShake(delta float64) {
 	}
 	progress := g.shakeTimer / g.shakeDuration
 	intensity := g.shakeMagnitude * progress
-	g.shakeOffsetX = (rand.Float64()*2 - 1) * intensity
-	g.shakeOffsetY = (rand.Float64()*2 - 1) * intensity
+	g.shakeOffsetX = (g.rng.Float64()*2 - 1) * intensity
+	g.shakeOffsetY = (g.rng.Float64()*2 - 1) * intensity
 }
 
-func (g *Game) handleChargePress(key ebiten.Key) {
-	if !(rsg >= 3 && !g.launched && !g.gameOver) {
-		return
-	}
-	added := basePowerGain
-	if g.lastComboKey != 0 && g.lastComboKey != key && g.comboTimer > 0 {
-		g.comboCount++
-	} else {
-		g.comboCount = 1
-	}
-	g.lastComboKey = key
-	g.comboTimer = comboTimeout
-	bonus := float64(g.comboCount-1) * comboBonus
-	added += bonus
-	g.power += added
-	g.totalFuel += added
-	if g.power > g.powerMax {
-		g.power = g.powerMax
-	}
-	g.tapCount++
-	if g.comboCount > g.maxCombo {
-		g.maxCombo = g.comboCount
-	}
-	playSFX(sfxChargeData)
-}
-
-func (g *Game) updateComboTimer(delta float64) {
-	if g.comboTimer > 0 {
-		g.comboTimer -= delta
-		if g.comboTimer <= 0 {
-			g.comboTimer = 0
-			g.comboCount = 0
-			g.lastComboKey = 0
-		}
+// keyboardInput hands the presses polled from the keyboard to the
+// simulation through the same sim.Input the replays and bots use. With the
+// auto-tap assist, holding a charge key keeps pressing at sim.AutoTapRate.
+type keyboardInput struct {
+	pending []sim.Button
+	out     []sim.Button
+
+	autoTap  bool
+	held     bool
+	last     sim.Button
+	nextAuto int
+}
+
+func (k *keyboardInput) Presses(f *sim.Flight) []sim.Button {
+	const interval = sim.Rate / sim.AutoTapRate
+	k.out = append(k.out[:0], k.pending...)
+	k.pending = k.pending[:0]
+	if n := len(k.out); n > 0 {
+		k.last = k.out[n-1]
+		k.nextAuto = f.StepIndex + interval
+	} else if k.autoTap && k.held && f.CanCharge() && f.StepIndex >= k.nextAuto {
+		k.last = otherButton(k.last)
+		k.out = append(k.out, k.last)
+		k.nextAuto = f.StepIndex + interval
+	}
+	return k.out
+}
+
+func otherButton(b sim.Button) sim.Button {
+	if b == sim.Left {
+		return sim.Right
 	}
+	return sim.Left
 }
 
-func (g *Game) startLaunch() {
-	g.launched = true
-	g.power_down = false
-	g.runDuration = 0
-	g.peakSpeed = 0
-	g.startScreenShake(0.6, 6)
-	g.comboCount = 0
-	g.comboTimer = 0
+// handleChargePress queues a press of a charge button, or of Stage, for
+// the next simulation step.
+func (g *Game) handleChargePress(b sim.Button) {
+	g.keys.pending = append(g.keys.pending, b)
 }
 
+// handleEvents turns what happened during a simulation step into sound,
+// effects and bookkeeping.
+func (g *Game) handleEvents(events []sim.Event) {
+	for _, e := range events {
+		switch e.Kind {
+		case sim.EventReady:
+			playSFX(sfxCountData)
+			g.caption(msg("caption.beep"))
+		case sim.EventChargeStart:
+			playSFX(sfxCountDownData)
+			g.caption(msg("caption.countdown"))
+			g.playCountdownVoice(e.Value)
+		case sim.EventCount:
+			g.playCountdownVoice(e.Value)
+		case sim.EventTap:
+			if e.Value > 1 {
+				g.burst("sparks", nozzleX, g.flight.Altitude+nozzleY+40, 0)
+			}
+			playSFX(sfxChargeData)
+		case sim.EventLaunch:
+			if g.launchSFXPlayer == nil || !g.launchSFXPlayer.IsPlaying() {
+				g.launchSFXPlayer = playSFX(sfxLaunchData)
+			}
+			g.caption(msg("caption.liftoff"))
+			g.startScreenShake(0.6, 6)
+		case sim.EventPowerDown:
+			if g.launchSFXPlayer != nil {
+				g.launchSFXPlayer.Pause()
+				g.launchSFXPlayer.Rewind()
+				g.launchSFXPlayer = nil
+			}
+			mixdown.stop(sfxLaunchData)
+			playSFX(sfxPowerDownData)
+			g.caption(msg("caption.power_down"))
+			g.burst("debris", nozzleX, g.flight.Altitude+nozzleY, 8)
+		case sim.EventBurnout:
+			playSFX(sfxCountData)
+			g.caption(msg("caption.burnout"))
+		case sim.EventStage:
+			playSFX(sfxChargeData)
+			g.caption(msg("caption.staging"))
+			g.burst("debris", nozzleX, g.flight.Altitude+nozzleY, 10)
+			g.startScreenShake(0.2, 3)
+		case sim.EventFailure:
+			playSFX(sfxLaunchData)
+			g.caption(msg("caption.explosion"))
+			g.burst("explosion", nozzleX, g.flight.Altitude+nozzleY-40, 0)
+			g.burst("debris", nozzleX, g.flight.Altitude+nozzleY-40, 40)
+			g.startScreenShake(1.2, 14)
+		case sim.EventGrade:
+			g.grade, g.gradeFlash = sim.Grade(e.Value), gradeFlashDuration
+		case sim.EventZone:
+			g.zoneBanner = zoneBannerDuration
+		case sim.EventLanded:
+			g.finalizeRun()
+		}
+	}
+}
+
+// finalizeRun wraps up a landed launch: it keeps the results for the
+// results panel and, for live runs, persists them.
 func (g *Game) finalizeRun() {
-	g.altitude = -5763
-	g.speed = 0
-	g.launched = false
-	g.power_down = false
-	g.power = 0
-	g.gameOver = true
-	g.start_count = false
-	duration := g.runDuration
-	prep := g.prepDuration
-	averageTPS := 0.0
-	if prep > 0 {
-		averageTPS = float64(g.tapCount) / prep
-	}
-	g.lastResult = ResultStats{
-		Altitude:      g.highscore,
-		PeakSpeed:     g.peakSpeed,
-		Duration:      duration,
-		PrepDuration:  prep,
-		TapCount:      g.tapCount,
-		MaxCombo:      g.maxCombo,
-		AverageTPS:    averageTPS,
-		FuelCollected: g.totalFuel,
-	}
-	g.comboCount = 0
-	g.comboTimer = 0
-	if g.highscore > g.saved_highscore {
-		g.saved_highscore = g.highscore
-		if err := g.saveHighscore(); err != nil {
-			log.Println("failed to save highscore:", err)
+	g.lastResult = g.flight.Result
+	g.prevAltitude = 0
+	g.credits = 0
+	g.burst("debris", nozzleX, nozzleY, 0)
+	if l, ok := g.bot.(bot.Learner); ok {
+		l.Learn(g.lastResult)
+	}
+	switch {
+	case g.mission != nil && g.tampered:
+		// A launch the console has changed neither passes nor earns
+		// stars, and the last launch's grade is not shown for it.
+		g.mission.passed, g.mission.stars, g.mission.improved = false, 0, false
+	case g.mission != nil:
+		g.gradeMission()
+	}
+	if g.persist && g.playback == nil && !g.tampered {
+		// A record's highlight runs on until the results panel is up.
+		if g.mission == nil && g.isRecord(g.lastResult) {
+			g.capture.saveLater(highlightLinger)
+		}
+		g.recordRun()
+		// Missions are tuned and judged their own way, and a sky picked
+		// from the menu is not the seed's, so both stay off the leaderboard.
+		if g.board != nil && g.recording != nil && g.mission == nil && g.weather == randomWeather {
+			g.board.submit(g.recording)
 		}
 	}
 }
 
+// highscorePayload is the highscore file. Score is the Normal best, which
+// is all the file held before difficulties existed.
+type highscorePayload struct {
+	Score  float64            `json:"score"`
+	Scores map[string]float64 `json:"scores,omitempty"`
+}
+
 func (g *Game) saveHighscore() error {
-	data, err := json.Marshal(struct {
-		Score float64 `json:"score"`
-	}{Score: g.saved_highscore})
+	data, err := json.Marshal(highscorePayload{
+		Score:  g.highscores[string(sim.Normal)],
+		Scores: g.highscores,
+	})
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(highscoreFile, data, 0o644)
+	return os.WriteFile(savePath(highscoreFile), data, 0o644)
 }
 
 func (g *Game) loadHighscore() {
-	data, err := os.ReadFile(highscoreFile)
+	data, err := os.ReadFile(savePath(highscoreFile))
 	if err != nil {
 		return
 	}
-	var payload struct {
-		Score float64 `json:"score"`
-	}
+	var payload highscorePayload
 	if err := json.Unmarshal(data, &payload); err != nil {
 		log.Println("failed to parse highscore file:", err)
 		return
 	}
-	g.saved_highscore = payload.Score
+	if payload.Scores != nil {
+		g.highscores = payload.Scores
+	}
+	if _, ok := g.highscores[string(sim.Normal)]; !ok && payload.Score > 0 {
+		g.highscores[string(sim.Normal)] = payload.Score
+	}
+	g.saved_highscore = g.highscores[g.flight.Config.Category()]
 }
 
 func (g *Game) playCountdownVoice(number int) {
+	if number > 0 {
+		g.caption(msg("caption.voice", number))
+	}
 	if len(voiceSamples) == 0 {
 		return
 	}
@@ -559,38 +716,77 @@ func (g *Game) playCountdownVoice(number int) {
 }
 
 func (g *Game) Update() error {
-	const deltaTime = 1.0 / 60.0
+	var err error
+	g.updateCapture()
+	switch {
+	case g.updateDebug():
+		// The console has the keyboard; the game waits.
+	case g.scene == sceneTitle:
+		err = g.title.update(g)
+	default:
+		g.updateFlight()
+	}
+
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		g.prevKeys[k] = ebiten.IsKeyPressed(k)
+	}
+
+	return err
+}
 
-	g.updateScreenShake(deltaTime)
-	g.updateComboTimer(deltaTime)
+func (g *Game) updateFlight() {
+	steps := g.clock.Tick()
+	if g.attract && g.updateAttract() {
+		return
+	}
+	if g.title != nil && !g.attract && g.JustPressed(ebiten.KeyEscape) {
+		g.showTitle()
+		return
+	}
+	g.pollInput()
+	for i := 0; i < steps; i++ {
+		g.step()
+	}
+	g.pollBoard()
+	g.animate(g.clock.FrameDelta())
+}
 
-	if g.gameOver {
-		if g.JustPressed(ebiten.KeyR) {
-			g.restartGame()
+// pollInput samples the keyboard once per tick. Charge presses are queued
+// and applied at the next simulation step so they land on the same step
+// whatever the tick rate.
+func (g *Game) pollInput() {
+	if g.JustPressed(ebiten.KeyR) {
+		g.restartGame()
+		return
+	}
+	if g.JustPressed(ebiten.KeyL) {
+		g.cycleLanguage()
+	}
+	if g.JustPressed(ebiten.KeyU) {
+		g.units = (g.units + 1) % 2
+	}
+	if g.flight.Over && g.board != nil {
+		if g.JustPressed(ebiten.KeyRight) {
+			g.boardTab = (g.boardTab + 1) % boardTabs
 		}
-		for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
-			g.prevKeys[k] = ebiten.IsKeyPressed(k)
+		if g.JustPressed(ebiten.KeyLeft) {
+			g.boardTab = (g.boardTab + boardTabs - 1) % boardTabs
 		}
-		return nil
 	}
-
-	// Move Clouds
-	g.bgoffset -= 30 * deltaTime
-	if g.bgoffset <= -float64(screenWidth) {
-		g.bgoffset = 0
+	if g.flight.Over || g.input != &g.keys {
+		return
 	}
-
-	// Ready, Set, Go Timer
-	if rsg < 3 {
-		g.rsg_timer += deltaTime
-		if g.rsg_timer >= 1 {
-			rsg++
-			playSFX(sfxCountData)
-			g.rsg_timer = 0
-			if rsg == 3 {
-				g.playCountdownVoice(count)
-			}
+	if g.flight.Launched {
+		// In flight the switch, which takes in both charge keys, stages
+		// the booster.
+		if g.switchJustPressed() {
+			g.handleChargePress(sim.Stage)
 		}
+		return
+	}
+	if g.scheme != nil {
+		g.scheme.update(g)
+		return
 	}
 
 	if ebiten.IsKeyPressed(ebiten.KeyZ) {
@@ -605,200 +801,148 @@ func (g *Game) Update() error {
 		g.x_down = 0
 	}
 
-	if g.JustPressed(ebiten.KeyR) {
-		g.restartGame()
-	}
-
+	g.keys.held = g.z_down == 1 || g.x_down == 1
 	if g.JustPressed(ebiten.KeyZ) {
-		g.handleChargePress(ebiten.KeyZ)
+		g.handleChargePress(sim.Left)
 	}
 	if g.JustPressed(ebiten.KeyX) {
-		g.handleChargePress(ebiten.KeyX)
+		g.handleChargePress(sim.Right)
 	}
+}
 
-	if rsg >= 3 && !g.launched {
-		if !g.start_count {
-			playSFX(sfxCountDownData)
-			g.start_count = true
-			g.prepDuration = 0
-			g.tapCount = 0
-			g.maxCombo = 0
-			g.comboCount = 0
-			g.comboTimer = 0
-			g.lastComboKey = 0
-			g.totalFuel = 0
-			g.playCountdownVoice(count)
-		}
-		g.prepDuration += deltaTime
-		if count > 0 {
-			g.count_timer += deltaTime
-			if g.count_timer >= 1 {
-				count--
-				g.count_timer = 0
-				if count >= 0 {
-					g.playCountdownVoice(count)
-				}
-			}
-		} else if !g.launched {
-			if g.launchSFXPlayer == nil || !g.launchSFXPlayer.IsPlaying() {
-				g.launchSFXPlayer = playSFX(sfxLaunchData)
-			}
-			g.startLaunch()
-		}
+// step advances the flight by one fixed simulation step, fed by whichever
+// input is playing: the keyboard, a replay or a bot.
+func (g *Game) step() {
+	g.prevAltitude = g.flight.Altitude
+	presses := g.input.Presses(g.flight)
+	if g.recording != nil {
+		g.recording.Record(g.flight.StepIndex, presses)
 	}
-
-	if g.launched && g.power <= 0 {
-		if g.launchSFXPlayer != nil {
-			g.launchSFXPlayer.Pause()
-			g.launchSFXPlayer.Rewind()
-			g.launchSFXPlayer = nil
-		}
-		if !g.power_down {
-			playSFX(sfxPowerDownData)
-			g.power_down = true
-		}
+	g.handleEvents(g.flight.Step(simStep, presses))
+	if g.mission != nil && g.mission.ghost != nil {
+		g.mission.ghost.step()
 	}
+}
 
-	if g.launched && !g.power_down {
-		g.particles = append(g.particles, Particle{
-			X:        240,
-			Y:        550,
-			Radius:   16 + rand.Float64()*6,
-			Velocity: 100 + rand.Float64()*30,
-			Opacity:  1.0,
-		})
-	}
-
-	for i := 0; i < len(g.particles); i++ {
-		p := &g.particles[i]
-		p.Y += p.Velocity * deltaTime
-		p.Opacity -= 0.015
-		p.Radius *= 1.01
-		if p.Opacity <= 0 {
-			g.particles = append(g.particles[:i], g.particles[i+1:]...)
-			i--
-		}
+func (g *Game) animate(frameDelta float64) {
+	g.updateScreenShake(frameDelta)
+	g.ageCaptions(frameDelta)
+	if g.zoneBanner > 0 {
+		g.zoneBanner -= frameDelta
+	}
+	if g.gradeFlash > 0 {
+		g.gradeFlash -= frameDelta
 	}
 
-	if g.launched {
-		g.runDuration += deltaTime
-		if g.speed < g.speedMax {
-			if g.power > 0 {
-				g.speed += 0.6 * deltaTime
-				g.power -= 60 * deltaTime
-				if g.power < 0 {
-					g.power = 0
-				}
-			} else if g.speed > g.gravity && g.altitude >= -5763 {
-				g.speed -= 2.4 * deltaTime
-			}
-		}
-		if g.speed > g.peakSpeed {
-			g.peakSpeed = g.speed
-		}
-		if g.altitude < g.speed && g.altitude >= -5763 {
-			g.altitude += g.speed
-		}
-		if g.altitude < -5763 {
-			g.altitude = -5763
-		}
-		alt := g.currentAltitude()
-		if alt > g.highscore {
-			g.highscore = alt
-		}
-		if g.power_down && g.altitude <= -5763 {
-			g.finalizeRun()
+	if !g.flight.Over && !g.access.ReducedMotion {
+		// Move Clouds, faster in a wind
+		drift := weatherDrift(g.flight.Weather)
+		g.skyTime += frameDelta * drift
+		g.bgoffset -= 30 * drift * frameDelta
+		if g.bgoffset <= -float64(screenWidth) {
+			g.bgoffset = 0
 		}
 	}
 
-	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
-		g.prevKeys[k] = ebiten.IsKeyPressed(k)
-	}
+	alt := g.viewAltitude()
+	g.updateEffects(alt, frameDelta)
+	g.camera.Follow(alt+rocketBaseY, g.targetZoom(), frameDelta)
+}
 
-	return nil
+// viewAltitude interpolates the rocket's altitude between the last two
+// simulation steps for drawing.
+func (g *Game) viewAltitude() float64 {
+	a := g.clock.Alpha()
+	return g.prevAltitude + (g.flight.Altitude-g.prevAltitude)*a
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	shakeX := g.shakeOffsetX
-	shakeY := g.shakeOffsetY
+// drawWorld draws the sky, the highscore marker, particles and the rocket,
+// shifted by the screen shake. rocketDY moves the rocket alone.
+func (g *Game) drawWorld(screen *ebiten.Image, shakeX, shakeY, rocketDY float64) {
 	textOffsetX := int(math.Round(shakeX))
-	textOffsetY := int(math.Round(shakeY))
-
-	// Draw Background
-	bgOp := &ebiten.DrawImageOptions{}
-	bgOp.GeoM.Translate(shakeX, g.altitude+shakeY)
-	screen.DrawImage(bg, bgOp)
-
-	// Draw Clouds
-	cloudWidth := clouds.Bounds().Dx()
 
-	cloudsOp := &ebiten.DrawImageOptions{}
-	cloudsOp.GeoM.Translate(g.bgoffset+shakeX, g.altitude+5740+shakeY)
-	screen.DrawImage(clouds, cloudsOp)
-
-	cloudsOp2 := &ebiten.DrawImageOptions{}
-	cloudsOp2.GeoM.Translate(g.bgoffset+float64(cloudWidth)+shakeX, g.altitude+5740+shakeY)
-	screen.DrawImage(clouds, cloudsOp2)
+	// Draw Backdrop
+	screen.Fill(spaceColor)
+	for _, l := range g.layers {
+		l.draw(screen, g, g.camera.Parallax(l.parallax), shakeX, shakeY)
+	}
 
-	// Draw the Highscore Record
+	// Draw the Highscore Record, or the goal altitude in modes that have one
+	markerAltitude := g.saved_highscore
+	if goal := g.flight.Goal; goal.Altitude > 0 {
+		markerAltitude = goal.Altitude
+	}
 	recordOp := &ebiten.DrawImageOptions{}
-	recordOp.GeoM.Translate(shakeX, g.altitude+6015-g.saved_highscore+shakeY)
+	g.camera.Place(recordOp, 0, markerAltitude+recordMarkerY, shakeX, shakeY)
 	screen.DrawImage(record, recordOp)
-	formatHighscore := fmt.Sprintf("%.0fm", g.saved_highscore)
+	formatHighscore := g.length(markerAltitude)
 	boundsHighscore := text.BoundString(myFont, formatHighscore)
 	textWidthHighscore := boundsHighscore.Dx()
+	_, labelY := g.camera.ToScreen(0, markerAltitude+recordLabelY)
 	xHighscore := (screenWidth - textWidthHighscore) / 2
 
 	customColor := color.RGBA{R: 9, G: 27, B: 162, A: 127}
-	text.Draw(screen, formatHighscore, myFont, xHighscore+textOffsetX, int(g.altitude+6080-g.saved_highscore+shakeY), customColor)
+	text.Draw(screen, formatHighscore, myFont, xHighscore+textOffsetX, int(labelY+shakeY), customColor)
 
 	// Draw Particles
-	for _, p := range g.particles {
-		alpha := uint8(p.Opacity * 255)
-		col := color.RGBA{255, 255, 255, alpha}
-		drawCircle(screen, p.X+shakeX, p.Y+shakeY, p.Radius, col)
+	g.fx.Draw(screen, particleSprites, func(x, y float64) (float64, float64, float64) {
+		sx, sy := g.camera.ToScreen(x, y)
+		return sx + shakeX, sy + shakeY, g.camera.Zoom
+	})
+
+	g.drawGhost(screen, shakeX, shakeY)
+
+	// Draw the Player, unless it has blown up
+	if g.flight.Failure == sim.NoFailure {
+		op := &ebiten.DrawImageOptions{}
+		g.camera.Place(op, rocketX, g.viewAltitude()+rocketBaseY, shakeX, shakeY+rocketDY)
+		screen.DrawImage(g.rocket, op)
 	}
+}
 
-	// Draw the Player
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(195+shakeX, 300+shakeY)
-	screen.DrawImage(player, op)
+func (g *Game) Draw(screen *ebiten.Image) {
+	g.drawScene(screen)
+	g.drawDebug(screen)
+	g.drawCapture(screen)
+}
+
+func (g *Game) drawScene(screen *ebiten.Image) {
+	if g.scene == sceneTitle {
+		g.title.draw(screen, g)
+		return
+	}
+	shakeX := g.shakeOffsetX
+	shakeY := g.shakeOffsetY
+	g.drawWorld(screen, shakeX, shakeY, 0)
 
 	// Draw Ready, Set, Go
 	readyOp := &ebiten.DrawImageOptions{}
 	readyOp.GeoM.Translate(float64((screenWidth-303)/2)+shakeX, 130+shakeY)
 
-	i := rsg
-	sx, sy := 0+i*303, 4
-	sub := ready_set_go.SubImage(image.Rect(sx, sy, sx+303, sy+118)).(*ebiten.Image)
-	screen.DrawImage(sub, readyOp)
+	i := g.flight.Ready
+	if g.lang.SpriteText {
+		sx, sy := 0+i*303, 4
+		sub := ready_set_go.SubImage(image.Rect(sx, sy, sx+303, sy+118)).(*ebiten.Image)
+		screen.DrawImage(sub, readyOp)
+	} else if i < 3 {
+		g.drawReady(screen, g.lang.T(fmt.Sprintf("ready.%d", i)), shakeX, shakeY)
+	}
 
 	// Draw Countdown
-	if rsg >= 3 {
+	if g.flight.Ready >= 3 {
 		countOp := &ebiten.DrawImageOptions{}
 		countOp.GeoM.Translate(float64((screenWidth-159)/2)-5+shakeX, 130+shakeY)
 
-		iCount := count
+		iCount := g.flight.Count
 		c_sx, c_sy := 0+iCount*159, 4
 		subCount := countdown.SubImage(image.Rect(c_sx, c_sy, c_sx+159, c_sy+118)).(*ebiten.Image)
 		screen.DrawImage(subCount, countOp)
 	}
 
-	// Draw Altitude
-	altitudeValue := ((g.altitude * -1) - 5763) * -1
-	formatAlt := fmt.Sprintf("%.0fm", math.Abs(altitudeValue))
-	bounds := text.BoundString(myFont, formatAlt)
-	textWidth := bounds.Dx()
-	screenWidth := 480
-	x := (screenWidth - textWidth) / 2
-
-	//text.Draw(screen, formatAlt, myFont, x, 80, color.White)
-	if g.launched {
-		drawTextWithOutline(screen, formatAlt, myFont, x+textOffsetX, 80+textOffsetY, color.White, color.Black)
-	} else if g.start_count && !g.launched {
-		drawTextWithOutline(screen, "Charge Your Rocket!", myFont, 60+textOffsetX, 80+textOffsetY, color.White, color.Black)
-
-		// Draw Z Button
+	// Draw Z and X Buttons, or the cue for a one-button scheme
+	if g.flight.Charging && !g.flight.Launched && g.scheme != nil {
+		g.scheme.draw(screen, g, shakeX, shakeY)
+	} else if g.flight.Charging && !g.flight.Launched {
 		zOp := &ebiten.DrawImageOptions{}
 		zOp.GeoM.Translate(280+shakeX, 270+shakeY)
 		zi := g.z_down
@@ -806,7 +950,6 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		zSub := zbutton.SubImage(image.Rect(zsx, zsy, zsx+135, zsy+135)).(*ebiten.Image)
 		screen.DrawImage(zSub, zOp)
 
-		// Draw X Button
 		xOp := &ebiten.DrawImageOptions{}
 		xOp.GeoM.Translate(310+shakeX, 340+shakeY)
 		xi := g.x_down
@@ -815,115 +958,112 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		screen.DrawImage(xSub, xOp)
 	}
 
-	// Draw Power Meter
-	if (rsg >= 3 && !g.power_down) || g.launched {
-		barWidth := 300.0
-		barHeight := 20.0
-		barX := (float64(screenWidth) - barWidth) / 2
-		barY := 560.0
-
-		ebitenutil.DrawRect(screen, barX+shakeX, barY+shakeY, barWidth, barHeight, color.RGBA{0, 0, 0, 180})
-
-		if g.powerMax > 0 {
-			fill := barWidth - 4
-			percent := g.power / g.powerMax
-			if percent > 1 {
-				percent = 1
-			}
-			if percent < 0 {
-				percent = 0
-			}
-			fillWidth := fill * percent
-			ebitenutil.DrawRect(screen, barX+2+shakeX, barY+2+shakeY, fillWidth, barHeight-4, color.RGBA{255, 165, 0, 255})
-
-			label := fmt.Sprintf("Fuel %3.0f%%", percent*100)
-			bounds := text.BoundString(myFont, label)
-			textX := int(barX + (barWidth-float64(bounds.Dx()))/2)
-			textY := int(barY) + int(barHeight) - 4
-			drawTextWithOutline(screen, label, myFont, textX+textOffsetX, textY+textOffsetY, color.White, color.Black)
-		}
-	}
+	// Draw HUD
+	g.hud.sync(g)
+	g.hud.root.Draw(screen, shakeX, shakeY)
 
-	if g.start_count && !g.launched && g.comboCount > 0 {
-		percent := g.comboTimer / comboTimeout
-		if percent < 0 {
-			percent = 0
-		}
-		if percent > 1 {
-			percent = 1
-		}
-		barWidth := 220.0
-		barHeight := 16.0
-		barX := (float64(screenWidth) - barWidth) / 2
-		barY := 520.0
-		ebitenutil.DrawRect(screen, barX-2+shakeX, barY-2+shakeY, barWidth+4, barHeight+4, color.RGBA{0, 0, 0, 180})
-		ebitenutil.DrawRect(screen, barX+shakeX, barY+shakeY, barWidth*percent, barHeight, color.RGBA{255, 94, 0, 255})
-		comboLabel := fmt.Sprintf("Combo x%d", g.comboCount)
-		drawTextWithOutline(screen, comboLabel, myFont, int(barX)+textOffsetX, int(barY)-10+textOffsetY, color.White, color.Black)
-		if g.prepDuration > 0 {
-			rate := float64(g.tapCount) / g.prepDuration
-			rateLabel := fmt.Sprintf("TPS %.1f", rate)
-			drawTextWithOutline(screen, rateLabel, myFont, 40+textOffsetX, int(barY)+textOffsetY+12, color.White, color.Black)
-		}
+	if g.flight.Over {
+		g.hud.results.Draw(screen, 0, 0)
 	}
-
-	if g.gameOver {
-		ebitenutil.DrawRect(screen, 0, 0, float64(screenWidth), float64(screenHeight), color.RGBA{0, 0, 0, 160})
-		panelW := 360.0
-		panelH := 280.0
-		panelX := (float64(screenWidth) - panelW) / 2
-		panelY := 150.0
-		ebitenutil.DrawRect(screen, panelX, panelY, panelW, panelH, color.RGBA{18, 22, 36, 230})
-		titleY := int(panelY) + 48
-		drawTextWithOutline(screen, "Flight Results", myFont, int(panelX)+46, titleY, color.White, color.Black)
-		instrY := titleY + 36
-		drawTextWithOutline(screen, "Press R to relaunch", myFont, int(panelX)+40, instrY, color.White, color.Black)
-		stats := []string{
-			fmt.Sprintf("Altitude: %.0fm", g.lastResult.Altitude),
-			fmt.Sprintf("Best: %.0fm", g.saved_highscore),
-			fmt.Sprintf("Flight Time: %.1fs", g.lastResult.Duration),
-			fmt.Sprintf("Prep Time: %.1fs", g.lastResult.PrepDuration),
-			fmt.Sprintf("Peak Speed: %.1f", g.lastResult.PeakSpeed),
-			fmt.Sprintf("Fuel Collected: %.0f", g.lastResult.FuelCollected),
-			fmt.Sprintf("Combo Max: x%d", g.lastResult.MaxCombo),
-			fmt.Sprintf("Taps: %d (%.1f TPS)", g.lastResult.TapCount, g.lastResult.AverageTPS),
-		}
-		lineY := instrY + 44
-		for _, line := range stats {
-			text.Draw(screen, line, myFont, int(panelX)+40, lineY, color.White)
-			lineY += 36
-		}
-
+	if g.attract {
+		demo := g.lang.T("title.demo")
+		w := text.BoundString(smallFont, demo).Dx()
+		drawTextWithOutline(screen, demo, smallFont, (screenWidth-w)/2, 30, color.White, color.Black)
 	}
-
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (w, h int) {
 	return screenWidth, screenHeight
 }
 
-func main() {
-	loadMusic()
+// newGame builds the simulation side of a game. The HUD, sky and particles
+// are attached by initPresentation so replays can run without a window.
+func newGame(seed uint64) *Game {
+	g := &Game{
+		flight:     sim.New(sim.DefaultConfig()),
+		prevKeys:   make(map[ebiten.Key]bool),
+		highscores: make(map[string]float64),
+		camera:     newCamera(),
+		seed:       seed,
+	}
+	g.restartGame()
+	return g
+}
+
+func (g *Game) initPresentation() {
+	loadFaces(g.access.textScale())
+	g.hud = newHUD(g.access)
+	g.layers = newLayers(bgSource)
+	g.initEffects()
+}
 
-	ebiten.SetWindowSize(screenWidth, screenHeight)
+// runGame opens the window and plays until it is closed. With a replay or a
+// bot the rocket is driven by it instead of the keyboard.
+func runGame(opts options, playback *sim.Replay) error {
+	loadAssets()
+	if !opts.Mute {
+		loadMusic()
+	}
+
+	ebiten.SetTPS(ticksPerSecond)
+	ebiten.SetWindowSize(opts.Width, opts.Height)
 	ebiten.SetWindowTitle("Go Game")
+	ebiten.SetFullscreen(opts.Fullscreen)
+
+	game, err := setupGame(opts, playback)
+	if err != nil {
+		return err
+	}
+	return ebiten.RunGame(game)
+}
 
-	game := &Game{
-		highscore:       0,
-		altitude:        -5763,
-		speed:           0,
-		speedMax:        30,
-		power:           0,
-		powerMax:        1200,
-		gravity:         -50,
-		start_count:     false,
-		power_down:      false,
-		saved_highscore: 0,
-		prevKeys:        make(map[ebiten.Key]bool),
+// setupGame builds a game ready to play, with its assets already loaded.
+func setupGame(opts options, playback *sim.Replay) (*Game, error) {
+	game := newGame(opts.Seed)
+	game.persist = true
+	game.access = opts.accessibility
+	if err := game.setLanguage(opts.Lang); err != nil {
+		return nil, err
+	}
+	game.units, _ = locale.ParseUnits(opts.Units)
+	game.loadProfile(opts.Player)
+	if playback != nil {
+		game.flight.Config = playback.Config
+		game.playback = sim.NewPlayback(playback)
+	} else {
+		game.flight.Config = game.build(opts.config())
+	}
+	game.scheme = newChargeScheme(game.flight.Scheme)
+	if playback == nil && opts.Bot != "" {
+		agent, err := bot.New(opts.Bot)
+		if err != nil {
+			return nil, err
+		}
+		// Bot launches are for watching, not for the record books.
+		game.bot = agent
+		game.persist = false
+	}
+	if opts.Leaderboard != "" && playback == nil {
+		game.board = newBoardLink(opts.Leaderboard, opts.Player, opts.Friends)
 	}
 	game.loadHighscore()
+	game.loadProgress()
+	game.loadLatency(opts)
+	game.weather = opts.Weather
+	game.capture = newRecorder(opts.Highlight)
+	if opts.Debug {
+		game.debug = &debugTools{}
+	}
+	game.initPresentation()
+	if playback == nil && game.bot == nil {
+		game.title = newTitleScreen(opts)
+		game.title.build(game)
+		game.scene = sceneTitle
+	}
+	game.restartGame()
+	return game, nil
+}
 
-	if err := ebiten.RunGame(game); err != nil {
-		log.Fatal(err)
-	}
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
 }
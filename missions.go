@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font"
+
+	"gitlab.com/Goodgis/go-game/campaign"
+	"gitlab.com/Goodgis/go-game/sim"
+	"gitlab.com/Goodgis/go-game/ui"
+)
+
+const (
+	missionDir   = "assets/missions"
+	progressFile = "campaign.json"
+	// ghostX is where the ghost rocket flies, left of the player's.
+	ghostX = 70.0
+)
+
+// missions are the campaign, in play order.
+var missions []*campaign.Mission
+
+func loadMissions() {
+	var err error
+	missions, err = campaign.LoadDir(missionDir)
+	if err != nil {
+		log.Println("failed to load missions:", err)
+		missions = nil
+	}
+}
+
+// missionRun is the mission being flown.
+type missionRun struct {
+	mission *campaign.Mission
+	ghost   *ghost
+
+	// passed and stars grade the last launch; improved is set when it
+	// beat the saved progress.
+	passed   bool
+	stars    int
+	improved bool
+}
+
+// with pairs a result with the ghost's for grading.
+func (run *missionRun) with(result sim.ResultStats) campaign.Run {
+	r := campaign.Run{Result: result}
+	if run.ghost != nil {
+		r.Ghost = &run.ghost.result
+	}
+	return r
+}
+
+// ghost is a recorded launch flown in step beside the player's.
+type ghost struct {
+	flight       *sim.Flight
+	playback     *sim.Playback
+	result       sim.ResultStats
+	prevAltitude float64
+}
+
+func newGhost(r *sim.Replay) (*ghost, error) {
+	result, err := sim.Simulate(r)
+	if err != nil {
+		return nil, err
+	}
+	f := sim.New(r.Config)
+	f.Seed = r.Seed
+	return &ghost{flight: f, playback: sim.NewPlayback(r), result: result}, nil
+}
+
+func (gh *ghost) reset() {
+	gh.flight.Reset()
+	gh.playback.Rewind()
+	gh.prevAltitude = 0
+}
+
+func (gh *ghost) step() {
+	if gh.flight.Over {
+		return
+	}
+	gh.prevAltitude = gh.flight.Altitude
+	gh.flight.Step(simStep, gh.playback.Presses(gh.flight))
+}
+
+func (g *Game) loadProgress() {
+	var err error
+	g.progress, err = campaign.LoadProgress(savePath(progressFile))
+	if err != nil {
+		log.Println("failed to load campaign progress:", err)
+	}
+}
+
+// startMission leaves the menu to fly m with the scheme and assists
+// chosen on the modes page.
+func (g *Game) startMission(m *campaign.Mission) {
+	run := &missionRun{mission: m}
+	if path := m.GhostPath(); path != "" {
+		r, err := sim.LoadReplay(path)
+		if err == nil {
+			run.ghost, err = newGhost(r)
+		}
+		if err != nil {
+			log.Println("failed to load ghost:", err)
+			return
+		}
+	}
+	o := g.title.opts
+	cfg := m.Config()
+	cfg.Scheme, _ = sim.ParseScheme(o.Scheme)
+	g.flight.Config = cfg.WithAssists(sim.Assists{AutoTap: o.AutoTap, WideCombo: o.WideCombo})
+	g.scheme = newChargeScheme(cfg.Scheme)
+	g.saved_highscore = g.highscores[g.flight.Config.Category()]
+	g.mission = run
+	g.scene = sceneFlight
+	g.restartGame()
+}
+
+// gradeMission scores the landed launch against the mission and keeps any
+// improvement.
+func (g *Game) gradeMission() {
+	run := g.mission
+	run.passed, run.stars = run.mission.Grade(run.with(g.lastResult))
+	run.improved = run.passed && g.progress.Record(run.mission.ID, run.stars)
+	if run.improved && g.persist {
+		if err := g.progress.Save(savePath(progressFile)); err != nil {
+			log.Println("failed to save campaign progress:", err)
+		}
+	}
+}
+
+// missionText is a mission's name or brief in the player's language: the
+// catalog message "mission.<id>.<field>" if there is one, else the text
+// from the mission file.
+func (g *Game) missionText(m *campaign.Mission, field, fallback string) string {
+	key := "mission." + m.ID + "." + field
+	if s := g.lang.T(key); s != key {
+		return s
+	}
+	return fallback
+}
+
+// conditionText describes a mission condition, such as "Reach 2,500m".
+func (g *Game) conditionText(c campaign.Condition) string {
+	value := func(v float64) string {
+		switch c.Stat {
+		case "altitude", "ghost_margin":
+			return g.length(v)
+		case "taps", "max_combo":
+			return g.lang.Number(v, 0)
+		}
+		return g.lang.Number(v, 1)
+	}
+	var parts []string
+	if c.Min != nil {
+		if c.Stat == "ghost_margin" && *c.Min == 0 {
+			parts = append(parts, g.lang.T("cond.ghost"))
+		} else {
+			parts = append(parts, g.lang.T("cond."+c.Stat+".min", value(*c.Min)))
+		}
+	}
+	if c.Max != nil {
+		parts = append(parts, g.lang.T("cond."+c.Stat+".max", value(*c.Max)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// missionLines are the results panel's lines for a mission launch: its
+// name, stars and every condition, ticked when met.
+func (g *Game) missionLines() []string {
+	run := g.mission
+	m := run.mission
+	r := run.with(g.lastResult)
+	mark := func(ok bool) string {
+		if ok {
+			return "[x] "
+		}
+		return "[ ] "
+	}
+	lines := []string{
+		g.missionText(m, "name", m.Name),
+		g.lang.T("mission.stars", run.stars, len(m.Stars)),
+	}
+	if m.Goal.Mode != sim.Classic {
+		lines = append(lines, mark(!g.lastResult.OutcomeOrClassic().Missed)+g.goalText(m.Goal))
+	}
+	for _, c := range m.Pass {
+		lines = append(lines, mark(c.Met(r))+g.conditionText(c))
+	}
+	for _, c := range m.Stars {
+		lines = append(lines, mark(run.passed && c.Met(r))+g.lang.T("mission.star", g.conditionText(c)))
+	}
+	if run.ghost != nil {
+		lines = append(lines, g.lang.T("mission.ghost", g.length(run.ghost.result.Altitude)))
+	}
+	return lines
+}
+
+// missionStatus says why a mission is locked or how well it has gone.
+func (g *Game) missionStatus(m *campaign.Mission) string {
+	for _, req := range m.Requires {
+		if !g.progress.Passed(req) {
+			return g.lang.T("mission.locked_requires", g.missionText(findMission(req), "name", req))
+		}
+	}
+	if g.progress.Total() < m.UnlockStars {
+		return g.lang.N("mission.locked_stars", m.UnlockStars, m.UnlockStars)
+	}
+	if !g.progress.Passed(m.ID) {
+		return g.lang.T("mission.new")
+	}
+	return g.lang.T("mission.best", g.progress.Stars[m.ID], len(m.Stars))
+}
+
+func findMission(id string) *campaign.Mission {
+	for _, m := range missions {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// drawGhost draws the ghost rocket, faded, where its flight has got to.
+func (g *Game) drawGhost(screen *ebiten.Image, shakeX, shakeY float64) {
+	if g.mission == nil || g.mission.ghost == nil {
+		return
+	}
+	gh := g.mission.ghost
+	alt := gh.prevAltitude + (gh.flight.Altitude-gh.prevAltitude)*g.clock.Alpha()
+	op := &ebiten.DrawImageOptions{}
+	g.camera.Place(op, ghostX-rocketPad, alt+rocketBaseY, shakeX, shakeY)
+	op.ColorScale.Scale(0.6, 0.8, 1, 1)
+	op.ColorScale.ScaleAlpha(0.45)
+	screen.DrawImage(rocketSprite(gh.playback.Replay.Loadout), op)
+}
+
+// wrapText breaks s into lines no wider than width in face.
+func wrapText(face font.Face, s string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		next := word
+		if line != "" {
+			next = line + " " + word
+		}
+		if line != "" && float64(text.BoundString(face, next).Dx()) > width {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// wrapped adds n lines of small text to the page that show s word-wrapped
+// to width; text beyond n lines is cut.
+func (p *menuPage) wrapped(n int, width float64, s func() string) []ui.Widget {
+	labels := make([]*ui.Label, n)
+	widgets := make([]ui.Widget, n)
+	for i := range labels {
+		labels[i] = &ui.Label{Plain: true, Face: smallFont}
+		widgets[i] = labels[i]
+	}
+	p.sync = append(p.sync, func() {
+		lines := wrapText(smallFont, s(), width)
+		for i, l := range labels {
+			l.Hidden = i >= len(lines)
+			if i < len(lines) {
+				l.Text = lines[i]
+			}
+		}
+	})
+	return widgets
+}
+
+// campaignPage is the mission-select map with the selected mission's
+// brief and status below it.
+func (t *titleScreen) campaignPage(g *Game) *menuPage {
+	p := &menuPage{}
+	if len(missions) == 0 {
+		return t.layout(g, p, "title.campaign", []ui.Widget{p.text(g.label("mission.none"))}, nil)
+	}
+	mm := &missionMap{Width: 380, Height: 220, g: g, OnActivate: g.startMission}
+	for i, m := range missions {
+		if g.progress.Unlocked(m) {
+			mm.Selected = i
+		}
+	}
+	selected := func() *campaign.Mission { return missions[mm.Selected] }
+	name := &ui.Label{Face: myFont}
+	p.sync = append(p.sync, func() { name.Text = g.missionText(selected(), "name", selected().Name) })
+	body := []ui.Widget{
+		p.text(func() string {
+			return g.lang.T("mission.total", g.progress.Total(), totalStars())
+		}),
+		mm,
+		name,
+	}
+	body = append(body, p.wrapped(2, 380, func() string { return g.missionText(selected(), "brief", selected().Brief) })...)
+	body = append(body, p.text(func() string { return g.missionStatus(selected()) }))
+	return t.layout(g, p, "title.campaign", body, nil)
+}
+
+// totalStars is how many stars the whole campaign offers.
+func totalStars() int {
+	n := 0
+	for _, m := range missions {
+		n += len(m.Stars)
+	}
+	return n
+}
+
+// missionMap draws the campaign as nodes placed by each mission's map
+// position and joined to the missions they require. Left and right step
+// through the missions; activating one that is unlocked flies it.
+type missionMap struct {
+	Hidden        bool
+	Width, Height float64
+	Selected      int
+	OnActivate    func(*campaign.Mission)
+
+	g       *Game
+	bounds  ui.Rect
+	focused bool
+}
+
+const (
+	mapPadding    = 24.0
+	mapNodeRadius = 12.0
+)
+
+var (
+	mapLocked   = color.RGBA{70, 74, 90, 255}
+	mapOpen     = color.RGBA{90, 170, 255, 255}
+	mapPassed   = color.RGBA{80, 200, 120, 255}
+	mapStarOn   = color.RGBA{255, 210, 60, 255}
+	mapStarOff  = color.RGBA{40, 44, 56, 255}
+	mapPathDone = color.RGBA{200, 200, 210, 200}
+)
+
+func (mm *missionMap) Measure(*ui.Theme) (float64, float64) { return mm.Width, mm.Height }
+func (mm *missionMap) Arrange(_ *ui.Theme, r ui.Rect)       { mm.bounds = r }
+func (mm *missionMap) Bounds() ui.Rect                      { return mm.bounds }
+func (mm *missionMap) Visible() bool                        { return !mm.Hidden }
+func (mm *missionMap) SetFocused(f bool)                    { mm.focused = f }
+
+// node returns the screen position of the mission at index i.
+func (mm *missionMap) node(i int) (float64, float64) {
+	r := mm.bounds
+	p := missions[i].Map
+	return r.X + mapPadding + p.X*(r.W-2*mapPadding), r.Y + mapPadding + p.Y*(r.H-2*mapPadding)
+}
+
+func (mm *missionMap) Draw(dst *ebiten.Image, t *ui.Theme, dx, dy float64) {
+	r := mm.bounds
+	vector.DrawFilledRect(dst, float32(r.X+dx), float32(r.Y+dy), float32(r.W), float32(r.H), color.RGBA{8, 12, 28, 200}, false)
+	progress := mm.g.progress
+	for i, m := range missions {
+		x, y := mm.node(i)
+		for _, req := range m.Requires {
+			for j, other := range missions {
+				if other.ID != req {
+					continue
+				}
+				px, py := mm.node(j)
+				clr := color.Color(mapLocked)
+				if progress.Passed(req) {
+					clr = mapPathDone
+				}
+				vector.StrokeLine(dst, float32(px+dx), float32(py+dy), float32(x+dx), float32(y+dy), 3, clr, true)
+			}
+		}
+	}
+	for i, m := range missions {
+		x, y := mm.node(i)
+		cx, cy := float32(x+dx), float32(y+dy)
+		clr := mapLocked
+		switch {
+		case progress.Passed(m.ID):
+			clr = mapPassed
+		case progress.Unlocked(m):
+			clr = mapOpen
+		}
+		if i == mm.Selected {
+			ring := color.Color(color.White)
+			if mm.focused {
+				ring = t.FocusColor
+			}
+			vector.StrokeCircle(dst, cx, cy, mapNodeRadius+5, 3, ring, true)
+		}
+		vector.DrawFilledCircle(dst, cx, cy, mapNodeRadius, clr, true)
+		label := fmt.Sprint(i + 1)
+		b := text.BoundString(smallFont, label)
+		t.DrawText(dst, label, smallFont, int(x+dx)-b.Dx()/2-b.Min.X, int(y+dy)-b.Dy()/2-b.Min.Y, color.White, color.Black)
+		for s := range len(m.Stars) {
+			star := mapStarOff
+			if s < progress.Stars[m.ID] {
+				star = mapStarOn
+			}
+			sx := cx + float32(s-1)*9
+			vector.DrawFilledCircle(dst, sx, cy+mapNodeRadius+8, 3.5, star, true)
+		}
+	}
+}
+
+func (mm *missionMap) Navigate(n ui.Nav) bool {
+	switch n {
+	case ui.NavLeft:
+		mm.Selected = (mm.Selected + len(missions) - 1) % len(missions)
+	case ui.NavRight:
+		mm.Selected = (mm.Selected + 1) % len(missions)
+	default:
+		return false
+	}
+	return true
+}
+
+// Point selects the mission nearest the cursor.
+func (mm *missionMap) Point(x, y float64) {
+	best := math.Inf(1)
+	for i := range missions {
+		nx, ny := mm.node(i)
+		if d := math.Hypot(nx-x, ny-y); d < best {
+			best, mm.Selected = d, i
+		}
+	}
+}
+
+func (mm *missionMap) Activate() {
+	m := missions[mm.Selected]
+	if mm.OnActivate != nil && mm.g.progress.Unlocked(m) {
+		mm.OnActivate(m)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+)
+
+// mixdown, when set, takes every sound the game plays instead of the audio
+// device and mixes it into a soundtrack, for rendering replays offline.
+var mixdown *mixer
+
+// mixer sums sounds into one 16-bit stereo track at sampleRate, the format
+// the game's players take. Sounds start wherever the track's clock, at,
+// stands when they are played.
+type mixer struct {
+	at      float64
+	samples []int32
+	decoded map[*byte][]byte
+	// last is where each file's latest playing starts and ends in
+	// samples, so it can be cut short.
+	last map[*byte][2]int
+}
+
+func newMixer() *mixer {
+	return &mixer{decoded: make(map[*byte][]byte), last: make(map[*byte][2]int)}
+}
+
+// offset is the track's clock as an index into samples.
+func (m *mixer) offset() int {
+	return 2 * int(m.at*sampleRate)
+}
+
+// play mixes pcm, 16-bit little-endian stereo, in from the clock on.
+func (m *mixer) play(pcm []byte) (start, end int) {
+	start = m.offset()
+	end = start + len(pcm)/2
+	if end > len(m.samples) {
+		m.samples = append(m.samples, make([]int32, end-len(m.samples))...)
+	}
+	for i := range len(pcm) / 2 {
+		m.samples[start+i] += int32(int16(binary.LittleEndian.Uint16(pcm[2*i:])))
+	}
+	return start, end
+}
+
+// playMP3 decodes an mp3 file's data, once, and plays it.
+func (m *mixer) playMP3(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	pcm, ok := m.decoded[&data[0]]
+	if !ok {
+		stream, err := mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+		if err == nil {
+			pcm, err = io.ReadAll(stream)
+		}
+		if err != nil {
+			log.Println("Error decoding sound:", err)
+		}
+		m.decoded[&data[0]] = pcm
+	}
+	start, end := m.play(pcm)
+	m.last[&data[0]] = [2]int{start, end}
+}
+
+// stop cuts short the latest playing of an mp3 file, as pausing its
+// player does in the game. It may be called on a nil mixer.
+func (m *mixer) stop(data []byte) {
+	if m == nil || len(data) == 0 {
+		return
+	}
+	span, ok := m.last[&data[0]]
+	if !ok {
+		return
+	}
+	pcm := m.decoded[&data[0]]
+	for i := max(span[0], m.offset()); i < span[1]; i++ {
+		m.samples[i] -= int32(int16(binary.LittleEndian.Uint16(pcm[2*(i-span[0]):])))
+	}
+	delete(m.last, &data[0])
+}
+
+// music lays the looping track under the first seconds of the soundtrack,
+// starting from from seconds into it.
+func (m *mixer) music(path string, from, seconds float64) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stream, err := mp3.DecodeWithSampleRate(sampleRate, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	pcm, err := io.ReadAll(stream)
+	if err != nil || len(pcm) < 4 {
+		return err
+	}
+	n := 2 * int(seconds*sampleRate)
+	if n > len(m.samples) {
+		m.samples = append(m.samples, make([]int32, n-len(m.samples))...)
+	}
+	loop := len(pcm) / 4
+	first := int(from*sampleRate) % loop
+	for i := range n / 2 {
+		j := 4 * ((first + i) % loop)
+		m.samples[2*i] += int32(int16(binary.LittleEndian.Uint16(pcm[j:])))
+		m.samples[2*i+1] += int32(int16(binary.LittleEndian.Uint16(pcm[j+2:])))
+	}
+	return nil
+}
+
+// writeWAV saves the first seconds of the soundtrack as a WAV file,
+// clipping where sounds add up past full scale.
+func (m *mixer) writeWAV(path string, seconds float64) error {
+	n := 2 * int(seconds*sampleRate)
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	header := []any{
+		[4]byte{'R', 'I', 'F', 'F'}, uint32(36 + 2*n), [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16), uint16(1), uint16(2),
+		uint32(sampleRate), uint32(4 * sampleRate), uint16(4), uint16(16),
+		[4]byte{'d', 'a', 't', 'a'}, uint32(2 * n),
+	}
+	for _, v := range header {
+		binary.Write(w, binary.LittleEndian, v)
+	}
+	data := make([]byte, 2*n)
+	for i, s := range m.samples[:min(n, len(m.samples))] {
+		s = min(max(s, math.MinInt16), math.MaxInt16)
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(s)))
+	}
+	w.Write(data)
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
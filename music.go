@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/text"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// musicTrack is the music the game loops; its beat map sits beside it
+// with the extension .beats.json.
+const musicTrack = "assets/sounds/bossa_nova.mp3"
+
+// musicBeat is the loaded track's beat map. Without one the Music scheme
+// is still played against the on-screen cue at sim.DefaultBeat.
+var musicBeat = sim.DefaultBeat
+
+// gradeFlashDuration is how long a Music press's grade stays on screen.
+const gradeFlashDuration = 0.4
+
+func beatMapPath(track string) string {
+	return strings.TrimSuffix(track, ".mp3") + ".beats.json"
+}
+
+// loadBeatMap reads the music's beat map. The loop length measured from
+// the track, when the music is playing, wins over the map's.
+func loadBeatMap() {
+	b, err := sim.LoadBeatMap(beatMapPath(musicTrack))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Println("failed to load beat map:", err)
+		}
+		return
+	}
+	if musicBeat.Length > 0 {
+		b.Length = musicBeat.Length
+	}
+	musicBeat = b
+}
+
+// musicTime is how far into the track the music is, or 0 when it is not
+// playing.
+func musicTime() float64 {
+	if musicPlayer == nil {
+		return 0
+	}
+	t := musicPlayer.Position().Seconds()
+	if musicBeat.Length > 0 {
+		t = math.Mod(t, musicBeat.Length)
+	}
+	return t
+}
+
+// launchBeat is the beat map for a Music launch starting now.
+func (g *Game) launchBeat() sim.Beat {
+	b := musicBeat
+	b.Start = musicTime()
+	b.Latency = g.latency
+	return b
+}
+
+// musicScheme presses on each tap of the switch, alternating buttons so
+// the keyboard's two charge keys both work; the flight grades each press
+// against the music.
+type musicScheme struct {
+	last sim.Button
+}
+
+func (s *musicScheme) reset() {
+	*s = musicScheme{}
+}
+
+func (s *musicScheme) update(g *Game) {
+	if !g.flight.CanCharge() || !g.switchJustPressed() {
+		return
+	}
+	s.last = otherButton(s.last)
+	g.handleChargePress(s.last)
+}
+
+var gradeColors = map[sim.Grade]color.RGBA{
+	sim.Perfect: {255, 230, 120, 255},
+	sim.Good:    {60, 200, 90, 255},
+	sim.Miss:    {220, 70, 60, 255},
+}
+
+// draw scrolls the coming beats leftwards into a target in the middle of
+// the track, with the Good and Perfect windows marked around it, and
+// shows the last press's grade above.
+func (s *musicScheme) draw(dst *ebiten.Image, g *Game, shakeX, shakeY float64) {
+	const w, h, y, speed = 300.0, 16.0, 400.0, 240.0 // speed in pixels a second
+	x := (float64(screenWidth) - w) / 2
+	mid := x + w/2
+	beat := g.flight.Beat
+	ebitenutil.DrawRect(dst, x+shakeX, y+shakeY, w, h, cueTrack)
+	ebitenutil.DrawRect(dst, mid-sim.GoodWindow*speed+shakeX, y+shakeY, 2*sim.GoodWindow*speed, h, color.RGBA{60, 140, 90, 255})
+	ebitenutil.DrawRect(dst, mid-sim.PerfectWindow*speed+shakeX, y+shakeY, 2*sim.PerfectWindow*speed, h, cueTarget)
+	if beat.BPM > 0 {
+		now := (float64(g.flight.StepIndex) + g.clock.Alpha()) * sim.StepDuration
+		next := now - beat.Off(now)
+		for t := next - beat.Period(); t-now < w/2/speed; t += beat.Period() {
+			if pos := mid + (t-now)*speed; pos >= x && pos <= x+w {
+				ebitenutil.DrawRect(dst, pos-3+shakeX, y-6+shakeY, 6, h+12, cueMarker)
+			}
+		}
+	}
+	if g.gradeFlash > 0 {
+		label := g.lang.T("grade." + g.grade.String())
+		drawTextWithOutline(dst, label, myFont, int(mid)-text.BoundString(myFont, label).Dx()/2+int(shakeX), int(y-24+shakeY), gradeColors[g.grade], color.Black)
+	}
+}
@@ -0,0 +1,224 @@
+// Package particles is a small pooled particle system. Effects are described
+// by EmitterDefs, usually loaded from JSON, and simulated in world
+// coordinates with y pointing up.
+package particles
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Range is an inclusive [min, max] interval sampled uniformly.
+type Range [2]float64
+
+func (r Range) sample(rng *rand.Rand) float64 {
+	return r[0] + (r[1]-r[0])*rng.Float64()
+}
+
+// RGBA is a non-premultiplied colour written as [r, g, b, a] in JSON.
+type RGBA [4]uint8
+
+func (c RGBA) lerp(o RGBA, t float64) color.NRGBA {
+	mix := func(a, b uint8) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*t) }
+	return color.NRGBA{mix(c[0], o[0]), mix(c[1], o[1]), mix(c[2], o[2]), mix(c[3], o[3])}
+}
+
+// Blend names how particles combine with what is already drawn.
+type Blend string
+
+const (
+	BlendAlpha    Blend = "alpha"
+	BlendAdditive Blend = "additive"
+)
+
+// EmitterDef describes one effect. Angles are in degrees with 90 pointing
+// up; Spread is the full width of the cone particles leave in.
+type EmitterDef struct {
+	Rate       float64 `json:"rate"`  // particles per second while active
+	Burst      int     `json:"burst"` // particles per one-shot Burst call
+	Lifetime   Range   `json:"lifetime"`
+	Speed      Range   `json:"speed"`
+	Direction  float64 `json:"direction"`
+	Spread     float64 `json:"spread"`
+	Jitter     Range   `json:"jitter"` // horizontal spawn offset
+	Gravity    float64 `json:"gravity"`
+	Drag       float64 `json:"drag"`
+	SizeStart  Range   `json:"size_start"`
+	SizeEnd    float64 `json:"size_end"` // multiple of the start size
+	ColorStart RGBA    `json:"color_start"`
+	ColorEnd   RGBA    `json:"color_end"`
+	Blend      Blend   `json:"blend"`
+	Sprite     string  `json:"sprite"`
+}
+
+// LoadDefs reads a JSON object mapping effect names to definitions.
+func LoadDefs(path string) (map[string]*EmitterDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defs := make(map[string]*EmitterDef)
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return defs, nil
+}
+
+// Particle is one live particle. Systems reuse them in place.
+type Particle struct {
+	X, Y   float64
+	VX, VY float64
+	Age    float64
+	Life   float64
+	Size   float64
+	def    *EmitterDef
+}
+
+// Emitter spawns particles from a definition at a steady rate while active.
+type Emitter struct {
+	Def    *EmitterDef
+	X, Y   float64
+	Active bool
+	// Scale multiplies the definition's rate, e.g. to follow throttle.
+	Scale float64
+
+	carry float64
+}
+
+// System owns a fixed-capacity pool of particles and the emitters feeding it.
+type System struct {
+	// Wind pushes every particle sideways, in pixels a second squared.
+	Wind float64
+
+	particles []Particle
+	emitters  []*Emitter
+	rng       *rand.Rand
+}
+
+// NewSystem allocates room for max live particles. Spawns beyond that are
+// dropped rather than growing the pool.
+func NewSystem(max int, seed uint64) *System {
+	return &System{
+		particles: make([]Particle, 0, max),
+		rng:       rand.New(rand.NewPCG(seed, seed^0x5eed)),
+	}
+}
+
+// NewEmitter registers a continuous emitter. It starts inactive.
+func (s *System) NewEmitter(def *EmitterDef) *Emitter {
+	e := &Emitter{Def: def, Scale: 1}
+	s.emitters = append(s.emitters, e)
+	return e
+}
+
+// Len returns the number of live particles.
+func (s *System) Len() int {
+	return len(s.particles)
+}
+
+// Clear removes every live particle and resets emitter accumulators.
+func (s *System) Clear() {
+	s.particles = s.particles[:0]
+	for _, e := range s.emitters {
+		e.carry = 0
+	}
+}
+
+// Burst spawns def.Burst particles at once, or n if n is positive.
+func (s *System) Burst(def *EmitterDef, x, y float64, n int) {
+	if n <= 0 {
+		n = def.Burst
+	}
+	for i := 0; i < n; i++ {
+		s.spawn(def, x, y)
+	}
+}
+
+func (s *System) spawn(def *EmitterDef, x, y float64) {
+	if def == nil || len(s.particles) == cap(s.particles) {
+		return
+	}
+	angle := (def.Direction + (s.rng.Float64()-0.5)*def.Spread) * math.Pi / 180
+	speed := def.Speed.sample(s.rng)
+	s.particles = append(s.particles, Particle{
+		X:    x + def.Jitter.sample(s.rng),
+		Y:    y,
+		VX:   math.Cos(angle) * speed,
+		VY:   math.Sin(angle) * speed,
+		Life: math.Max(def.Lifetime.sample(s.rng), 1e-3),
+		Size: def.SizeStart.sample(s.rng),
+		def:  def,
+	})
+}
+
+// Update runs emitters and advances every particle by delta seconds.
+func (s *System) Update(delta float64) {
+	for _, e := range s.emitters {
+		if !e.Active || e.Def == nil {
+			continue
+		}
+		e.carry += e.Def.Rate * e.Scale * delta
+		for e.carry >= 1 {
+			s.spawn(e.Def, e.X, e.Y)
+			e.carry--
+		}
+	}
+	for i := 0; i < len(s.particles); {
+		p := &s.particles[i]
+		p.Age += delta
+		if p.Age >= p.Life {
+			// Swap the dead particle with the last live one.
+			last := len(s.particles) - 1
+			s.particles[i] = s.particles[last]
+			s.particles = s.particles[:last]
+			continue
+		}
+		damp := math.Exp(-p.def.Drag * delta)
+		p.VX = p.VX*damp + s.Wind*delta
+		p.VY = p.VY*damp + p.def.Gravity*delta
+		p.X += p.VX * delta
+		p.Y += p.VY * delta
+		i++
+	}
+}
+
+// Projector maps a world point to the screen and reports the scale there.
+type Projector func(x, y float64) (sx, sy, scale float64)
+
+// Draw renders every particle with the sprite named in its definition.
+func (s *System) Draw(dst *ebiten.Image, sprites map[string]*ebiten.Image, project Projector) {
+	op := &ebiten.DrawImageOptions{}
+	for i := range s.particles {
+		p := &s.particles[i]
+		img := sprites[p.def.Sprite]
+		if img == nil {
+			continue
+		}
+		t := p.Age / p.Life
+		size := p.Size * (1 + (p.def.SizeEnd-1)*t)
+		if p.def.SizeEnd == 0 {
+			size = p.Size
+		}
+		sx, sy, scale := project(p.X, p.Y)
+		w := float64(img.Bounds().Dx())
+		k := size * scale / w
+
+		op.GeoM.Reset()
+		op.GeoM.Translate(-w/2, -float64(img.Bounds().Dy())/2)
+		op.GeoM.Scale(k, k)
+		op.GeoM.Translate(sx, sy)
+		op.ColorScale.Reset()
+		op.ColorScale.ScaleWithColor(p.def.ColorStart.lerp(p.def.ColorEnd, t))
+		op.Blend = ebiten.BlendSourceOver
+		if p.def.Blend == BlendAdditive {
+			op.Blend = ebiten.BlendLighter
+		}
+		dst.DrawImage(img, op)
+	}
+}
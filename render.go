@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// renderOptions are the render command's own flags.
+type renderOptions struct {
+	fps           int
+	width, height int
+	// frames is the directory PNG frames go to, y4m the file, or "-" for
+	// stdout, the video stream goes to and wav the soundtrack's file. Any
+	// left empty is not written.
+	frames string
+	y4m    string
+	wav    string
+	// tail is how many seconds to keep rendering once the rocket lands,
+	// for the results panel.
+	tail float64
+}
+
+// renderCommand renders a replay frame by frame, as fast as the machine
+// allows rather than in real time, so the same replay always gives the
+// same footage.
+func renderCommand(opts options, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gorocket [flags] render [render flags] <replay>\n\n"+
+			"Rendering draws on the GPU and opens a window that previews the footage;\n"+
+			"on a machine without a display, run it under xvfb-run.\n\nRender flags:")
+		fs.PrintDefaults()
+	}
+	var ro renderOptions
+	size := fs.String("size", fmt.Sprintf("%dx%d", screenWidth, screenHeight), "frame `size` as WIDTHxHEIGHT; the game is scaled to fit")
+	fs.IntVar(&ro.fps, "fps", 30, "frames per second of game time")
+	fs.StringVar(&ro.frames, "frames", "", "`directory` to write numbered PNG frames to")
+	fs.StringVar(&ro.y4m, "y4m", "", "`file` to write a YUV4MPEG2 stream to, - for stdout")
+	fs.StringVar(&ro.wav, "wav", "", "`file` to write the soundtrack to")
+	fs.Float64Var(&ro.tail, "tail", 3, "`seconds` to keep rendering after landing")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "render takes exactly one replay file")
+		return 2
+	}
+	if _, err := fmt.Sscanf(*size, "%dx%d", &ro.width, &ro.height); err != nil {
+		fmt.Fprintf(stderr, "bad size %q, want WIDTHxHEIGHT\n", *size)
+		return 2
+	}
+	if err := ro.validate(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	r, err := sim.LoadReplay(fs.Arg(0))
+	if err == nil {
+		err = renderReplay(opts, ro, r, stdout, stderr)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func (ro renderOptions) validate() error {
+	switch {
+	case ro.frames == "" && ro.y4m == "" && ro.wav == "":
+		return errors.New("nothing to render: give -frames, -y4m or -wav")
+	case ro.fps <= 0:
+		return fmt.Errorf("fps %d must be positive", ro.fps)
+	case ro.width <= 0 || ro.height <= 0:
+		return fmt.Errorf("frame size %dx%d must be positive", ro.width, ro.height)
+	case ro.y4m != "" && (ro.width%2 != 0 || ro.height%2 != 0):
+		return fmt.Errorf("a y4m stream needs an even frame size, not %dx%d", ro.width, ro.height)
+	case ro.tail < 0:
+		return errors.New("tail must not be negative")
+	}
+	return nil
+}
+
+// renderReplay plays r back one frame of game time per frame drawn. The
+// game draws on the GPU, so this still needs a window, which shows the
+// footage as it is made; under a headless server run it in a virtual
+// display such as xvfb-run.
+func renderReplay(opts options, ro renderOptions, r *sim.Replay, stdout, stderr io.Writer) error {
+	loadAssets()
+	ticksPerSecond = ro.fps
+	mixdown = newMixer()
+	game, err := setupGame(opts, r)
+	if err != nil {
+		return err
+	}
+	rd := &renderer{
+		game:   game,
+		opts:   ro,
+		canvas: ebiten.NewImage(screenWidth, screenHeight),
+		frame:  ebiten.NewImage(ro.width, ro.height),
+		pix:    make([]byte, 4*ro.width*ro.height),
+		tail:   int(ro.tail * float64(ro.fps)),
+		log:    stderr,
+	}
+	if ro.frames != "" {
+		if err := os.MkdirAll(ro.frames, 0o755); err != nil {
+			return err
+		}
+	}
+	if ro.y4m != "" {
+		w := stdout
+		if ro.y4m != "-" {
+			f, err := os.Create(ro.y4m)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		rd.y4m = newY4MWriter(w, ro.width, ro.height, ro.fps)
+	}
+
+	ebiten.SetTPS(ebiten.SyncWithFPS)
+	ebiten.SetVsyncEnabled(false)
+	ebiten.SetRunnableOnUnfocused(true)
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("Go Game - rendering")
+	if err := ebiten.RunGameWithOptions(rd, &ebiten.RunGameOptions{InitUnfocused: true}); err != nil {
+		return err
+	}
+	if rd.err != nil {
+		return rd.err
+	}
+	if rd.y4m != nil {
+		if err := rd.y4m.w.Flush(); err != nil {
+			return err
+		}
+	}
+	seconds := float64(rd.written) / float64(ro.fps)
+	if ro.wav != "" {
+		if !opts.Mute {
+			from := 0.0
+			if r.Config.Scheme == sim.Music {
+				from = r.Config.Beat.Start
+			}
+			if err := mixdown.music(musicTrack, from, seconds); err != nil {
+				fmt.Fprintln(stderr, "rendering without music:", err)
+			}
+		}
+		if err := mixdown.writeWAV(ro.wav, seconds); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stderr, "rendered %d frames, %.1fs at %d fps\n", rd.written, seconds, ro.fps)
+	return nil
+}
+
+// renderer is the ebiten game that drives a replay for renderReplay. Each
+// Update advances the flight by one frame and the Draw after it writes that
+// frame out.
+type renderer struct {
+	game   *Game
+	opts   renderOptions
+	canvas *ebiten.Image
+	frame  *ebiten.Image
+	pix    []byte
+	y4m    *y4mWriter
+	log    io.Writer
+
+	// ready is set by Update and cleared once Draw has written the frame.
+	ready   bool
+	written int
+	tail    int
+	err     error
+}
+
+func (rd *renderer) Update() error {
+	if rd.err != nil {
+		return rd.err
+	}
+	if rd.ready {
+		return nil
+	}
+	g := rd.game
+	if g.flight.Over {
+		if rd.tail <= 0 {
+			return ebiten.Termination
+		}
+		rd.tail--
+	}
+	mixdown.at = float64(rd.written) / float64(rd.opts.fps)
+	for range g.clock.Tick() {
+		g.step()
+	}
+	g.animate(g.clock.FrameDelta())
+	rd.ready = true
+	return nil
+}
+
+func (rd *renderer) Draw(screen *ebiten.Image) {
+	rd.canvas.Clear()
+	rd.game.drawScene(rd.canvas)
+	rd.frame.Fill(color.Black)
+	rd.frame.DrawImage(rd.canvas, fitOptions(rd.canvas.Bounds(), rd.frame.Bounds()))
+	screen.DrawImage(rd.frame, fitOptions(rd.frame.Bounds(), screen.Bounds()))
+	if !rd.ready || rd.err != nil {
+		return
+	}
+	rd.ready = false
+	rd.frame.ReadPixels(rd.pix)
+	if err := rd.write(); err != nil {
+		rd.err = err
+		return
+	}
+	rd.written++
+	if rd.written%(rd.opts.fps*5) == 0 {
+		fmt.Fprintf(rd.log, "%d frames\n", rd.written)
+	}
+}
+
+// write saves the frame in rd.pix wherever it is wanted.
+func (rd *renderer) write() error {
+	if rd.opts.frames != "" {
+		img := &image.RGBA{Pix: rd.pix, Stride: 4 * rd.opts.width, Rect: image.Rect(0, 0, rd.opts.width, rd.opts.height)}
+		path := filepath.Join(rd.opts.frames, fmt.Sprintf("frame-%06d.png", rd.written))
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		if err := writeFile(path, func(f *os.File) error { return enc.Encode(f, img) }); err != nil {
+			return err
+		}
+	}
+	if rd.y4m != nil {
+		return rd.y4m.frame(rd.pix)
+	}
+	return nil
+}
+
+func (rd *renderer) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+// fitOptions scales src to fit inside dst, keeping its shape, and centres
+// it there.
+func fitOptions(src, dst image.Rectangle) *ebiten.DrawImageOptions {
+	scale := min(float64(dst.Dx())/float64(src.Dx()), float64(dst.Dy())/float64(src.Dy()))
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate((float64(dst.Dx())-scale*float64(src.Dx()))/2, (float64(dst.Dy())-scale*float64(src.Dy()))/2)
+	return op
+}
+
+// y4mWriter writes frames as a YUV4MPEG2 stream, which ffmpeg and most
+// other encoders read from a pipe: full-range BT.601 colour with the
+// chroma halved both ways.
+type y4mWriter struct {
+	w             *bufio.Writer
+	width, height int
+	y, cb, cr     []byte
+}
+
+func newY4MWriter(w io.Writer, width, height, fps int) *y4mWriter {
+	y := &y4mWriter{
+		w:      bufio.NewWriter(w),
+		width:  width,
+		height: height,
+		y:      make([]byte, width*height),
+		cb:     make([]byte, width*height/4),
+		cr:     make([]byte, width*height/4),
+	}
+	fmt.Fprintf(y.w, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C420jpeg XCOLORRANGE=FULL\n", width, height, fps)
+	return y
+}
+
+// frame writes one frame of RGBA pixels.
+func (y *y4mWriter) frame(pix []byte) error {
+	w := y.width
+	for by := 0; by < y.height; by += 2 {
+		for bx := 0; bx < w; bx += 2 {
+			var cb, cr int
+			for _, i := range []int{by*w + bx, by*w + bx + 1, (by+1)*w + bx, (by+1)*w + bx + 1} {
+				p := pix[4*i:]
+				luma, u, v := color.RGBToYCbCr(p[0], p[1], p[2])
+				y.y[i] = luma
+				cb += int(u)
+				cr += int(v)
+			}
+			c := by/2*w/2 + bx/2
+			y.cb[c], y.cr[c] = uint8((cb+2)/4), uint8((cr+2)/4)
+		}
+	}
+	y.w.WriteString("FRAME\n")
+	y.w.Write(y.y)
+	y.w.Write(y.cb)
+	_, err := y.w.Write(y.cr)
+	return err
+}
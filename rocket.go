@@ -0,0 +1,304 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/particles"
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// The rocket is composited from hangar parts onto a canvas wider than
+// player.png by rocketPad on each side, room for extra boosters and fins.
+// rocketCore is the core's centre line on the canvas.
+const (
+	rocketPad    = 30
+	rocketWidth  = 90 + 2*rocketPad
+	rocketHeight = 275
+	rocketX      = 195 - rocketPad
+	rocketCore   = rocketPad + 45
+
+	// noseBase is where the stock nose cone meets the core, and noseHalf
+	// the core's half width there, outline included.
+	noseBase = 52
+	noseHalf = 23
+	// partOutline is the width of the black line round drawn parts.
+	partOutline = 4
+)
+
+// stockOrange is the colour of player.png's paint, which the Paint slot
+// replaces.
+var stockOrange = color.RGBA{254, 144, 0, 255}
+
+// profilesDir holds one hangar profile per player name.
+const profilesDir = "profiles"
+
+var (
+	hangarParts *hangar.Catalog
+	// playerSource is player.png as decoded, read pixel by pixel to paint.
+	playerSource image.Image
+
+	rocketSprites = make(map[sim.Loadout]*ebiten.Image)
+	paintedBodies = make(map[string]*ebiten.Image)
+	exhaustDefs   = make(map[string]*particles.EmitterDef)
+)
+
+func loadHangar() {
+	var err error
+	hangarParts, err = hangar.Load("assets/hangar.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// rocketSprite returns the rocket l describes, compositing it the first
+// time it is asked for.
+func rocketSprite(l sim.Loadout) *ebiten.Image {
+	if img, ok := rocketSprites[l]; ok {
+		return img
+	}
+	c := hangarParts
+	paint := partColor(c.Get(l, hangar.Paint), stockOrange)
+	fill := color.RGBA{paint.R * 4 / 5, paint.G * 4 / 5, paint.B * 4 / 5, 255}
+	body := painted(c.Get(l, hangar.Paint))
+	img := ebiten.NewImage(rocketWidth, rocketHeight)
+
+	// Fins go on first so the body overlaps their roots. They sit on the
+	// outermost boosters, or on the core when there are none.
+	edge := 43.0
+	switch c.Get(l, hangar.Body).ID {
+	case "slim":
+		edge = 20
+	case "heavy":
+		edge = 57
+	}
+	switch c.Get(l, hangar.Fins).ID {
+	case "delta":
+		drawFins(img, edge, [][2]float64{{4, 196}, {4, 262}, {-18, 268}, {-18, 248}}, fill)
+	case "swept":
+		drawFins(img, edge, [][2]float64{{4, 180}, {4, 228}, {-16, 272}, {-16, 238}}, fill)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	switch c.Get(l, hangar.Body).ID {
+	case "slim":
+		// The core alone, without its boosters.
+		op.GeoM.Translate(rocketPad+26, 0)
+		img.DrawImage(body.SubImage(image.Rect(26, 0, 66, rocketHeight)).(*ebiten.Image), op)
+	case "heavy":
+		// A second pair of boosters strapped on outside the first.
+		booster := func(x0, dx int) {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(float64(rocketPad+x0+dx), 100)
+			img.DrawImage(body.SubImage(image.Rect(x0, 100, x0+30, rocketHeight)).(*ebiten.Image), op)
+		}
+		booster(0, -14)
+		booster(60, 14)
+		fallthrough
+	default:
+		op.GeoM.Translate(rocketPad, 0)
+		img.DrawImage(body, op)
+	}
+
+	if nose := c.Get(l, hangar.Nose); !nose.Stock() {
+		img.SubImage(image.Rect(rocketCore-noseHalf-2, 0, rocketCore+noseHalf+2, noseBase)).(*ebiten.Image).Clear()
+		var p vector.Path
+		p.MoveTo(rocketCore-noseHalf, noseBase)
+		switch nose.ID {
+		case "needle":
+			p.LineTo(rocketCore-noseHalf, noseBase-6)
+			p.LineTo(rocketCore-6, 22)
+			p.LineTo(rocketCore, partOutline)
+			p.LineTo(rocketCore+6, 22)
+			p.LineTo(rocketCore+noseHalf, noseBase-6)
+		case "dome":
+			const steps = 16
+			for i := 1; i < steps; i++ {
+				a := math.Pi * float64(i) / steps
+				p.LineTo(float32(rocketCore-noseHalf*math.Cos(a)), float32(noseBase-26*math.Sin(a)))
+			}
+		}
+		p.LineTo(rocketCore+noseHalf, noseBase)
+		p.Close()
+		fillPath(img, &p, color.White)
+		strokePath(img, &p, color.Black)
+	}
+
+	rocketSprites[l] = img
+	return img
+}
+
+// drawFins draws a pair of fins, mirrored about the core, on a body whose
+// side is edge from its centre line. pts are the left fin's corners,
+// across from the side and down from the top of the canvas.
+func drawFins(dst *ebiten.Image, edge float64, pts [][2]float64, clr color.Color) {
+	for _, side := range []float64{-1, 1} {
+		var p vector.Path
+		for i, pt := range pts {
+			x, y := float32(rocketCore+side*(edge-pt[0])), float32(pt[1])
+			if i == 0 {
+				p.MoveTo(x, y)
+			} else {
+				p.LineTo(x, y)
+			}
+		}
+		p.Close()
+		fillPath(dst, &p, clr)
+		strokePath(dst, &p, color.Black)
+	}
+}
+
+func fillPath(dst *ebiten.Image, p *vector.Path, clr color.Color) {
+	vs, is := p.AppendVerticesAndIndicesForFilling(nil, nil)
+	drawPath(dst, vs, is, clr, ebiten.FillRuleNonZero)
+}
+
+func strokePath(dst *ebiten.Image, p *vector.Path, clr color.Color) {
+	vs, is := p.AppendVerticesAndIndicesForStroke(nil, nil, &vector.StrokeOptions{Width: partOutline, LineJoin: vector.LineJoinRound})
+	drawPath(dst, vs, is, clr, ebiten.FillRuleFillAll)
+}
+
+func drawPath(dst *ebiten.Image, vs []ebiten.Vertex, is []uint16, clr color.Color, rule ebiten.FillRule) {
+	r, g, b, a := clr.RGBA()
+	for i := range vs {
+		vs[i].SrcX, vs[i].SrcY = 0.5, 0.5
+		vs[i].ColorR = float32(r) / 0xffff
+		vs[i].ColorG = float32(g) / 0xffff
+		vs[i].ColorB = float32(b) / 0xffff
+		vs[i].ColorA = float32(a) / 0xffff
+	}
+	dst.DrawTriangles(vs, is, whitePixel, &ebiten.DrawTrianglesOptions{AntiAlias: true, FillRule: rule})
+}
+
+// partColor is a paint's or exhaust's colour, or def for the stock one.
+func partColor(p hangar.Part, def color.RGBA) color.RGBA {
+	if p.Color == nil {
+		return def
+	}
+	return color.RGBA{p.Color[0], p.Color[1], p.Color[2], 255}
+}
+
+// painted is player.png with its orange swapped for paint's colour,
+// keeping the shading.
+func painted(paint hangar.Part) *ebiten.Image {
+	if paint.Color == nil {
+		return player
+	}
+	if img, ok := paintedBodies[paint.ID]; ok {
+		return img
+	}
+	to := partColor(paint, stockOrange)
+	b := playerSource.Bounds()
+	img := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(playerSource.At(x, y)).(color.NRGBA)
+			if int(c.R) > int(c.G)+60 && c.B < 100 {
+				shade := (float64(c.R) + float64(c.G)) / (float64(stockOrange.R) + float64(stockOrange.G))
+				c.R = uint8(math.Min(255, float64(to.R)*shade))
+				c.G = uint8(math.Min(255, float64(to.G)*shade))
+				c.B = uint8(math.Min(255, float64(to.B)*shade))
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	paintedBodies[paint.ID] = ebiten.NewImageFromImage(img)
+	return paintedBodies[paint.ID]
+}
+
+// exhaustDef is the exhaust effect in l's colour, fading to a darker
+// shade of it instead of the stock flame's red.
+func exhaustDef(l sim.Loadout) *particles.EmitterDef {
+	part := hangarParts.Get(l, hangar.Exhaust)
+	if part.Color == nil {
+		return particleDefs["exhaust"]
+	}
+	if def, ok := exhaustDefs[part.ID]; ok {
+		return def
+	}
+	def := *particleDefs["exhaust"]
+	c := part.Color
+	def.ColorStart = particles.RGBA{c[0], c[1], c[2], 255}
+	def.ColorEnd = particles.RGBA{c[0] / 3, c[1] / 3, c[2] / 3, 0}
+	exhaustDefs[part.ID] = &def
+	return &def
+}
+
+// build fits the player's rocket to a launch tuned as cfg.
+func (g *Game) build(cfg sim.Config) sim.Config {
+	return hangarParts.Rocket(g.fitted).Apply(cfg)
+}
+
+// applyLoadout dresses the rocket about to fly: a replay's own loadout, or
+// the player's.
+func (g *Game) applyLoadout() {
+	l := g.fitted
+	if g.playback != nil {
+		l = g.playback.Replay.Loadout
+	}
+	g.rocket = rocketSprite(l)
+	g.exhaust.Def = exhaustDef(l)
+}
+
+// profilePath is where name's hangar profile is saved. Anything but
+// letters, digits, '-' and '_' is dropped from the file name.
+func profilePath(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r < 128 && (r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return -1
+	}, name)
+	if name == "" {
+		name = "player"
+	}
+	return savePath(filepath.Join(profilesDir, name+".json"))
+}
+
+// loadProfile reads the player's hangar profile and fits their rocket.
+func (g *Game) loadProfile(name string) {
+	var err error
+	g.profilePath = profilePath(name)
+	g.profile, err = hangar.LoadProfile(g.profilePath)
+	if err != nil {
+		log.Println("failed to load hangar profile:", err)
+	}
+	g.refit()
+}
+
+func (g *Game) saveProfile() {
+	if !g.persist {
+		return
+	}
+	if err := g.profile.Save(g.profilePath); err != nil {
+		log.Println("failed to save hangar profile:", err)
+	}
+}
+
+// refit works out the loadout the player flies from what they own now.
+func (g *Game) refit() {
+	g.fitted = g.profile.Fitted(hangarParts, earnedAchievements())
+}
+
+// earnedAchievements is the set of achievement ids the run history earns.
+func earnedAchievements() map[string]bool {
+	entries, err := loadHistory()
+	if err != nil {
+		log.Println("failed to load run history:", err)
+	}
+	earned := make(map[string]bool)
+	for i, ok := range unlockedAchievements(entries) {
+		if ok {
+			earned[achievements[i].id] = true
+		}
+	}
+	return earned
+}
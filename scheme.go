@@ -0,0 +1,258 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// chargeScheme turns one-button input into charge presses for the players
+// who cannot mash two keys. Every scheme presses through handleChargePress
+// so the fuel and combo rules are the same as for the keyboard.
+type chargeScheme interface {
+	reset()
+	// update reads the switch for one tick.
+	update(g *Game)
+	// draw shows the cue presses are timed against.
+	draw(dst *ebiten.Image, g *Game, shakeX, shakeY float64)
+}
+
+// newChargeScheme returns the scheme's input handler, or nil for the
+// two-button Alternate scheme the keyboard handles directly.
+func newChargeScheme(s sim.Scheme) chargeScheme {
+	switch s {
+	case sim.Rhythm:
+		return &rhythmScheme{}
+	case sim.HoldRelease:
+		return &holdScheme{}
+	case sim.Scanning:
+		return &scanScheme{}
+	case sim.Music:
+		return &musicScheme{}
+	}
+	return nil
+}
+
+var (
+	cueTrack  = color.RGBA{0, 0, 0, 180}
+	cueTarget = color.RGBA{60, 200, 90, 255}
+	cueHit    = color.RGBA{255, 230, 120, 255}
+	cueMarker = color.RGBA{255, 165, 0, 255}
+)
+
+// switchKeys all act as the single switch.
+var switchKeys = []ebiten.Key{ebiten.KeySpace, ebiten.KeyEnter, ebiten.KeyZ, ebiten.KeyX}
+
+func (g *Game) switchJustPressed() bool {
+	for _, k := range switchKeys {
+		if g.JustPressed(k) {
+			return true
+		}
+	}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonRightBottom) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) switchHeld() bool {
+	for _, k := range switchKeys {
+		if ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonRightBottom) {
+			return true
+		}
+	}
+	return false
+}
+
+// rhythmScheme continues the combo for each tap within sim.RhythmWindow of
+// a fresh metronome beat. Taps off the beat still add fuel but restart the
+// combo.
+type rhythmScheme struct {
+	lastBeat int
+	last     sim.Button
+	flash    float64
+}
+
+func (r *rhythmScheme) reset() {
+	*r = rhythmScheme{lastBeat: -1}
+}
+
+func (r *rhythmScheme) update(g *Game) {
+	r.flash = math.Max(0, r.flash-g.clock.FrameDelta())
+	if !g.flight.CanCharge() || !g.switchJustPressed() {
+		return
+	}
+	t := g.flight.PrepDuration
+	beat := int(math.Round(t / sim.RhythmBeat))
+	b := r.last
+	if b == sim.NoButton {
+		b = sim.Left
+	}
+	if math.Abs(t-float64(beat)*sim.RhythmBeat) <= sim.RhythmWindow && beat != r.lastBeat {
+		b = otherButton(r.last)
+		r.lastBeat = beat
+		r.flash = 0.15
+	}
+	r.last = b
+	g.handleChargePress(b)
+}
+
+// draw swings a marker across a track so that it crosses the target at the
+// centre exactly on each beat.
+func (r *rhythmScheme) draw(dst *ebiten.Image, g *Game, shakeX, shakeY float64) {
+	const w, h, y = 300.0, 16.0, 400.0
+	x := (float64(screenWidth) - w) / 2
+	ebitenutil.DrawRect(dst, x+shakeX, y+shakeY, w, h, cueTrack)
+	half := w / 2 * math.Sin(math.Pi*sim.RhythmWindow/sim.RhythmBeat)
+	target := color.Color(cueTarget)
+	if r.flash > 0 {
+		target = cueHit
+	}
+	ebitenutil.DrawRect(dst, x+w/2-half+shakeX, y+shakeY, 2*half, h, target)
+	pos := x + w/2 + w/2*math.Sin(math.Pi*g.flight.PrepDuration/sim.RhythmBeat)
+	ebitenutil.DrawRect(dst, pos-4+shakeX, y-6+shakeY, 8, h+12, cueMarker)
+}
+
+// Hold meter windows, as fractions of a full meter.
+const (
+	holdPerfect = 0.8
+	holdGood    = 0.6
+	holdTop     = 0.95
+)
+
+// holdScheme fills a meter while the switch is held and, on release,
+// presses a burst whose length depends on how close to full the meter was
+// without overshooting.
+type holdScheme struct {
+	holding bool
+	held    float64
+	burst   int
+	next    float64
+	last    sim.Button
+}
+
+func (s *holdScheme) reset() {
+	*s = holdScheme{}
+}
+
+// meter rises over sim.HoldCycle and falls back again if held too long.
+func (s *holdScheme) meter() float64 {
+	p := math.Mod(s.held/sim.HoldCycle, 2)
+	if p > 1 {
+		p = 2 - p
+	}
+	return p
+}
+
+func (s *holdScheme) update(g *Game) {
+	dt := g.clock.FrameDelta()
+	if !g.flight.CanCharge() {
+		s.holding, s.burst = false, 0
+		return
+	}
+	if !s.holding && s.burst == 0 && g.switchJustPressed() {
+		s.holding, s.held = true, 0
+	}
+	if s.holding {
+		s.held += dt
+		if !g.switchHeld() {
+			s.holding = false
+			switch m := s.meter(); {
+			case m >= holdPerfect && m <= holdTop:
+				s.burst = 4
+			case m >= holdGood && m <= holdTop:
+				s.burst = 2
+			default:
+				s.burst = 1
+			}
+			s.next = 0
+		}
+	}
+	if s.burst > 0 {
+		s.next -= dt
+		if s.next <= 0 {
+			s.last = otherButton(s.last)
+			g.handleChargePress(s.last)
+			s.burst--
+			s.next = sim.HoldBurstGap
+		}
+	}
+}
+
+func (s *holdScheme) draw(dst *ebiten.Image, g *Game, shakeX, shakeY float64) {
+	const w, h, x, y = 28.0, 160.0, 350.0, 270.0
+	ebitenutil.DrawRect(dst, x+shakeX, y+shakeY, w, h, cueTrack)
+	ebitenutil.DrawRect(dst, x+shakeX, y+h*(1-holdTop)+shakeY, w, h*(holdTop-holdGood), color.RGBA{60, 140, 90, 255})
+	ebitenutil.DrawRect(dst, x+shakeX, y+h*(1-holdTop)+shakeY, w, h*(holdTop-holdPerfect), cueTarget)
+	fill := 0.0
+	if s.holding {
+		fill = s.meter()
+	}
+	ebitenutil.DrawRect(dst, x+4+shakeX, y+h*(1-fill)+shakeY, w-8, h*fill, cueMarker)
+}
+
+// scanScheme steps a highlight between the two charge buttons every
+// sim.ScanStep; the switch presses whichever is lit, once per step.
+type scanScheme struct {
+	lastStep int
+}
+
+func (s *scanScheme) reset() {
+	s.lastStep = -1
+}
+
+func (s *scanScheme) lit(g *Game) (int, sim.Button) {
+	step := int(g.flight.PrepDuration / sim.ScanStep)
+	if step%2 == 0 {
+		return step, sim.Left
+	}
+	return step, sim.Right
+}
+
+func (s *scanScheme) update(g *Game) {
+	if !g.flight.CanCharge() || !g.switchJustPressed() {
+		return
+	}
+	step, b := s.lit(g)
+	if step == s.lastStep {
+		return
+	}
+	s.lastStep = step
+	g.handleChargePress(b)
+}
+
+func (s *scanScheme) draw(dst *ebiten.Image, g *Game, shakeX, shakeY float64) {
+	step, b := s.lit(g)
+	for i, img := range []*ebiten.Image{zbutton, xbutton} {
+		x, y := 90.0+float64(i)*165, 280.0
+		frame := 0
+		if b == sim.Button(i+1) {
+			ebitenutil.DrawRect(dst, x-6+shakeX, y-6+shakeY, 147, 147, cueTarget)
+			if step == s.lastStep {
+				frame = 1
+			}
+		}
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(x+shakeX, y+shakeY)
+		dst.DrawImage(img.SubImage(image.Rect(frame*135, 2, frame*135+135, 137)).(*ebiten.Image), op)
+	}
+}
@@ -0,0 +1,148 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Grade is how close to the beat of the music a press landed.
+type Grade int
+
+const (
+	Miss Grade = iota
+	Good
+	Perfect
+)
+
+func (g Grade) String() string {
+	switch g {
+	case Good:
+		return "good"
+	case Perfect:
+		return "perfect"
+	}
+	return "miss"
+}
+
+// Timing and worth of the Music scheme's grades. The windows are either
+// side of the beat in seconds; the gains are multiples of BasePowerGain.
+// A miss burns MissLoss of them and breaks the streak, so mashing through
+// the windows costs more than it earns.
+const (
+	PerfectWindow = 0.05
+	GoodWindow    = 0.12
+	PerfectGain   = 12
+	GoodGain      = 6
+	MissLoss      = 1
+)
+
+// Beat is where the beats of the music fall. BPM and Offset come from the
+// track's beat map; Start and Latency are filled in for each launch.
+type Beat struct {
+	BPM float64 `json:"bpm"`
+	// Offset is the time of the first beat into the track, in seconds.
+	Offset float64 `json:"offset"`
+	// Length is the track's length in seconds when it loops, so beats
+	// start again from Offset on every pass; zero when it does not.
+	Length float64 `json:"length,omitempty"`
+	// Start is how far into the track the music was at step 0.
+	Start float64 `json:"start,omitempty"`
+	// Latency is how late the player's presses arrive, from calibration;
+	// it is taken off every press before grading.
+	Latency float64 `json:"latency,omitempty"`
+}
+
+// DefaultBeat stands in when a track has no beat map: 120 bpm counted from
+// its start.
+var DefaultBeat = Beat{BPM: 120}
+
+// MaxLatency bounds Latency in seconds; beyond half a calibration beat a
+// late tap cannot be told from an early one.
+const MaxLatency = 0.25
+
+// LoadBeatMap reads a track's beat map.
+func LoadBeatMap(path string) (Beat, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Beat{}, err
+	}
+	var b Beat
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Beat{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := b.Check(); err != nil {
+		return Beat{}, fmt.Errorf("beat map %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Check reports beat maps that have no beats to press on.
+func (b Beat) Check() error {
+	if b.BPM <= 0 {
+		return fmt.Errorf("bpm %g must be positive", b.BPM)
+	}
+	if b.Offset < 0 || b.Length < 0 || (b.Length > 0 && b.Offset >= b.Length) {
+		return fmt.Errorf("offset %g must be inside the track", b.Offset)
+	}
+	return nil
+}
+
+// CheckLaunch reports a launch's beat that is not track's beat map, with
+// a start inside the track and a latency calibration could have measured.
+func (b Beat) CheckLaunch(track Beat) error {
+	if b.BPM != track.BPM || b.Offset != track.Offset || b.Length != track.Length {
+		return fmt.Errorf("beat %g bpm from %gs looping at %gs is not the track's %g bpm from %gs looping at %gs",
+			b.BPM, b.Offset, b.Length, track.BPM, track.Offset, track.Length)
+	}
+	if b.Start < 0 || (b.Length > 0 && b.Start >= b.Length) {
+		return fmt.Errorf("start %g must be inside the track", b.Start)
+	}
+	if math.Abs(b.Latency) > MaxLatency {
+		return fmt.Errorf("latency %gs must be within %gs", b.Latency, MaxLatency)
+	}
+	return nil
+}
+
+// Period is the time between beats in seconds.
+func (b Beat) Period() float64 {
+	return 60 / b.BPM
+}
+
+// Off returns how far a press made t seconds after step 0 landed from
+// the nearest beat: negative when early, positive when late.
+func (b Beat) Off(t float64) float64 {
+	if b.BPM <= 0 {
+		return math.Inf(1)
+	}
+	pos := b.Start + t - b.Latency
+	if b.Length > 0 {
+		pos = math.Mod(pos, b.Length)
+		if pos < 0 {
+			pos += b.Length
+		}
+	}
+	p := b.Period()
+	off := math.Mod(pos-b.Offset, p)
+	if off < 0 {
+		off += p
+	}
+	if off > p/2 {
+		off -= p
+	}
+	return off
+}
+
+// GradeAt grades a press made t seconds after step 0 and returns the time
+// of the beat it was graded against, on the same clock as t.
+func (b Beat) GradeAt(t float64) (Grade, float64) {
+	off := b.Off(t)
+	switch {
+	case math.Abs(off) <= PerfectWindow:
+		return Perfect, t - off
+	case math.Abs(off) <= GoodWindow:
+		return Good, t - off
+	}
+	return Miss, t - off
+}
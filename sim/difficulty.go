@@ -0,0 +1,157 @@
+package sim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Difficulty names a preset tuning. Scores are only comparable within one.
+type Difficulty string
+
+const (
+	Easy   Difficulty = "easy"
+	Normal Difficulty = "normal"
+	Hard   Difficulty = "hard"
+	// Custom is any tuning that is not exactly one of the presets.
+	Custom Difficulty = "custom"
+)
+
+// Difficulties lists the presets in menu order.
+var Difficulties = []Difficulty{Easy, Normal, Hard, Custom}
+
+// ParseDifficulty accepts a preset name in any case.
+func ParseDifficulty(s string) (Difficulty, error) {
+	d := Difficulty(strings.ToLower(s))
+	for _, known := range Difficulties {
+		if d == known {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown difficulty %q, want one of %v", s, Difficulties)
+}
+
+// Assists make charging easier. Assisted runs are ranked apart from
+// unassisted ones on the same preset.
+type Assists struct {
+	// AutoTap charges while a button is held, alternating presses at
+	// AutoTapRate.
+	AutoTap bool `json:"auto_tap,omitempty"`
+	// WideCombo stretches the combo window by WideComboFactor.
+	WideCombo bool `json:"wide_combo,omitempty"`
+}
+
+const (
+	// AutoTapRate is how many presses a second holding a button is worth;
+	// comfortably inside the normal combo window but slower than a keen
+	// masher.
+	AutoTapRate = 6
+	// WideComboFactor is how much the WideCombo assist stretches the
+	// combo window.
+	WideComboFactor = 1.5
+)
+
+// Any reports whether any assist is on.
+func (a Assists) Any() bool {
+	return a.AutoTap || a.WideCombo
+}
+
+// Preset returns the tuning for d. Custom starts from Normal.
+func Preset(d Difficulty) Config {
+	cfg := DefaultConfig()
+	switch d {
+	case Easy:
+		cfg.CountFrom = 15
+		cfg.ComboTimeout = 0.5
+		cfg.BasePowerGain = 7
+		cfg.Gravity = -40
+		cfg.Risks = Risks{RedLine: 0.95, StageWindow: 2}
+	case Hard:
+		cfg.CountFrom = 7
+		cfg.ComboTimeout = 0.25
+		cfg.BasePowerGain = 4
+		cfg.Gravity = -60
+		cfg.Risks = Risks{RedLine: 0.85, MaxSpeed: 12.2, StageWindow: 0.8}
+	}
+	return cfg
+}
+
+// WithAssists returns c with assists a in place of any it had.
+func (c Config) WithAssists(a Assists) Config {
+	c.Assists = a
+	return c
+}
+
+// ComboWindow is the time allowed between alternating presses once
+// assists are taken into account, never so short that the charge scheme
+// cannot keep a combo going. In the Music scheme it reaches to the end
+// of the next beat's Good window.
+func (c Config) ComboWindow() float64 {
+	if c.Scheme == Music && c.Beat.BPM > 0 {
+		return c.Beat.Period() + GoodWindow
+	}
+	w := c.ComboTimeout
+	if c.Assists.WideCombo {
+		w *= WideComboFactor
+	}
+	return max(w, c.Scheme.minComboWindow())
+}
+
+// Difficulty reports which preset c is on the rocket it was built for, or
+// Custom if it matches none.
+func (c Config) Difficulty() Difficulty {
+	return c.DifficultyOn(c.Rocket)
+}
+
+// DifficultyOn reports which preset c is once rocket has been applied to
+// it, or Custom if it matches none.
+func (c Config) DifficultyOn(rocket Rocket) Difficulty {
+	c.Assists = Assists{}
+	c.Scheme = Alternate
+	c.Goal = Goal{}
+	c.Beat = Beat{}
+	c.Weather = Weather{}
+	for _, d := range Difficulties[:3] {
+		p := rocket.Apply(Preset(d))
+		if c.Risks == (Risks{}) {
+			// Launches from before failures carry no risks and were
+			// flown on the preset all the same.
+			p.Risks = Risks{}
+		}
+		if p == c {
+			return d
+		}
+	}
+	return Custom
+}
+
+// Category is the key scores are ranked under: the difficulty, marked
+// when assists were used, then the charge scheme if it is not Alternate
+// and the goal's key if the mode is not Classic. A preset built for a
+// parts rocket is tagged as CategoryOn tags it.
+func (c Config) Category() string {
+	return c.CategoryOn(c.Rocket)
+}
+
+// CategoryOn is Category for c flown on rocket, a rocket built from parts:
+// the preset c is once rocket has been applied to it, tagged "+parts"
+// unless rocket is the stock one.
+func (c Config) CategoryOn(rocket Rocket) string {
+	d := c.DifficultyOn(rocket)
+	if d == Custom || rocket.stock() {
+		return c.category(string(d))
+	}
+	return c.category(string(d) + "+parts")
+}
+
+func (c Config) category(cat string) string {
+	if c.Assists.Any() {
+		cat += "+assist"
+	}
+	if c.Scheme != Alternate {
+		cat += "/" + string(c.Scheme)
+	}
+	if c.Goal.Mode != Classic {
+		cat += ":" + c.Goal.Key()
+	}
+	return cat
+}
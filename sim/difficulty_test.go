@@ -0,0 +1,47 @@
+package sim
+
+import "testing"
+
+// charger alternates the charge buttons every few steps until the tank
+// holds share of PowerMax, then waits for the launch, staging as soon as
+// the booster is spent.
+type charger struct {
+	share float64
+	next  int
+	last  Button
+}
+
+func (c *charger) Presses(f *Flight) []Button {
+	if f.StagePending() {
+		return []Button{Stage}
+	}
+	if !f.CanCharge() || f.StepIndex < c.next || f.Power >= c.share*f.PowerMax {
+		return nil
+	}
+	c.next = f.StepIndex + 6
+	c.last = otherButton(c.last)
+	return []Button{c.last}
+}
+
+// TestGravityPullsTheClimb flies the same presses on the same tank under
+// each preset's gravity: the heavier it is, the lower the rocket gets.
+func TestGravityPullsTheClimb(t *testing.T) {
+	for _, share := range []float64{0.5, 0.8} {
+		var last float64
+		for i, d := range []Difficulty{Easy, Normal, Hard} {
+			cfg := DefaultConfig()
+			cfg.Gravity = Preset(d).Gravity
+			res, err := Run(cfg, 1, &charger{share: share}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Failure != NoFailure {
+				t.Fatalf("%s at %.0f%%: failed with %s", d, share*100, res.Failure)
+			}
+			if i > 0 && res.Altitude >= last {
+				t.Errorf("%s at %.0f%%: reached %.0f m, not below the lighter gravity's %.0f m", d, share*100, res.Altitude, last)
+			}
+			last = res.Altitude
+		}
+	}
+}
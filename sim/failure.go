@@ -0,0 +1,125 @@
+package sim
+
+import "math"
+
+// Failure is how a launch came to grief.
+type Failure string
+
+const (
+	// NoFailure is a launch that flew its whole course.
+	NoFailure Failure = ""
+	// EngineFailure is the engine giving out after a launch on a tank
+	// charged into the red.
+	EngineFailure Failure = "engine"
+	// StagingFailure is a booster dropped while it was still burning, or
+	// an upper stage left unlit too long after the booster burned out.
+	StagingFailure Failure = "staging"
+	// StructuralFailure is the airframe tearing apart above its top speed.
+	StructuralFailure Failure = "structure"
+)
+
+// Failures lists every failure; EventFailure's Value indexes it.
+var Failures = []Failure{EngineFailure, StagingFailure, StructuralFailure}
+
+// Risks are what can make a launch fail. The zero value never fails, as
+// no launch could before failures existed.
+type Risks struct {
+	// RedLine is the fraction of PowerMax beyond which the tank is in the
+	// red. Launching on more fuel than that risks an engine failure, up
+	// to MaxEngineRisk on a brim-full tank.
+	RedLine float64 `json:",omitempty"`
+	// MaxSpeed is the airframe's limit in metres per 1/60s; flying faster
+	// tears it apart.
+	MaxSpeed float64 `json:",omitempty"`
+	// StageWindow is how many seconds after the booster burns out the
+	// upper stage must be staged; 0 flies the whole tank in one stage.
+	StageWindow float64 `json:",omitempty"`
+}
+
+const (
+	// MaxEngineRisk is the chance of an engine failure on a full tank.
+	MaxEngineRisk = 0.5
+	// BoosterShare is the part of the charged fuel the booster burns
+	// before the upper stage takes over.
+	BoosterShare = 0.5
+	// StageGrace is how many seconds of booster fuel may be left when
+	// staging without it counting as early; what is left is lost.
+	StageGrace = 0.25
+)
+
+// RedLineFuel is the fuel beyond which the tank is in the red, or
+// PowerMax when there is no red line.
+func (c Config) RedLineFuel() float64 {
+	if c.Risks.RedLine <= 0 {
+		return c.PowerMax
+	}
+	return c.Risks.RedLine * c.PowerMax
+}
+
+// InRedZone reports whether the tank holds more than the red line.
+func (f *Flight) InRedZone() bool {
+	return f.Power > f.RedLineFuel()
+}
+
+// EngineRisk is the chance that launching now ends in an engine failure.
+func (f *Flight) EngineRisk() float64 {
+	if !f.InRedZone() {
+		return 0
+	}
+	red := f.RedLineFuel()
+	return MaxEngineRisk * (f.Power - red) / (f.PowerMax - red)
+}
+
+// StagePending reports whether the booster is spent and the flight is
+// waiting on a Stage press to light the upper stage.
+func (f *Flight) StagePending() bool {
+	return f.Launched && f.Risks.StageWindow > 0 && !f.Staged && f.Booster <= 0 && f.Power > 0 && f.Failure == NoFailure
+}
+
+// rollEngine decides at launch whether an overcharged engine will fail.
+// The roll hashes the launch's seed with its charge rather than drawing
+// from a generator, so a replay fails the same way while the same presses
+// on another seed may not.
+func (f *Flight) rollEngine() bool {
+	risk := f.EngineRisk()
+	if risk <= 0 {
+		return false
+	}
+	h := f.Seed ^ uint64(f.StepIndex)<<32 ^ uint64(f.TapCount) ^ math.Float64bits(f.TotalFuel)
+	// splitmix64's finaliser spreads the nearby inputs over the range.
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return float64(h>>11)/(1<<53) < risk
+}
+
+// stage separates the booster. Doing so while it still has more than
+// StageGrace of fuel fails the launch.
+func (f *Flight) stage() {
+	if !f.Launched || f.Risks.StageWindow <= 0 || f.Staged || f.Failure != NoFailure {
+		return
+	}
+	if f.Booster > f.BurnRate*StageGrace {
+		f.fail(StagingFailure)
+		return
+	}
+	f.Power -= f.Booster
+	f.Booster = 0
+	f.Staged = true
+	f.emit(EventStage, 0)
+}
+
+// fail ends the launch in failure: the engine is out and whatever fuel is
+// left is lost. The wreck then falls as a spent rocket would.
+func (f *Flight) fail(kind Failure) {
+	f.Failure = kind
+	f.Power = 0
+	f.Booster = 0
+	for i, k := range Failures {
+		if k == kind {
+			f.emit(EventFailure, i)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package sim
+
+import "testing"
+
+// TestStockSpeedLimit checks that the stock rocket, on a full tank, stays
+// under every preset's speed limit; only a rebuilt one can outrun it.
+func TestStockSpeedLimit(t *testing.T) {
+	for _, d := range []Difficulty{Easy, Normal, Hard} {
+		cfg := Preset(d)
+		if _, failure := cfg.Ceiling(cfg.PowerMax); failure != NoFailure {
+			t.Errorf("%s: a full tank failed with %s", d, failure)
+		}
+	}
+}
+
+// TestEngineRollSeeded flies the same presses onto a brim-full tank under
+// many seeds: at MaxEngineRisk some engines must fail and some must not.
+func TestEngineRollSeeded(t *testing.T) {
+	const runs = 100
+	failed := 0
+	for seed := uint64(1); seed <= runs; seed++ {
+		res, err := Run(Preset(Normal), seed, &charger{share: 1}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch res.Failure {
+		case EngineFailure:
+			failed++
+		case NoFailure:
+		default:
+			t.Fatalf("seed %d: failed with %s", seed, res.Failure)
+		}
+	}
+	if failed < runs/4 || failed > runs*3/4 {
+		t.Errorf("%d of %d engines failed on a full tank, want about half", failed, runs)
+	}
+}
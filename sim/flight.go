@@ -0,0 +1,520 @@
+// Package sim is the launch simulation: the ready/set/go and countdown
+// timers, the charge economy, and the flight itself. It has no window,
+// audio or keyboard so launches can be replayed, verified and played by
+// bots headlessly; the game turns the Events it returns into sound and
+// effects.
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rate is the number of steps per second the simulation is designed for.
+// Replays record step numbers, so every consumer steps at this rate.
+const Rate = 60
+
+// StepDuration is the length of one step in seconds.
+const StepDuration = 1.0 / Rate
+
+// speedUnit converts speed, which is reported in metres per 1/60s, into
+// metres per second.
+const speedUnit = 60.0
+
+// Button is one of the two charge buttons, which alternating between
+// builds the combo, or the Stage button pressed in flight.
+type Button int
+
+const (
+	NoButton Button = iota
+	Left
+	Right
+	// Stage separates the booster so the upper stage can light.
+	Stage
+)
+
+func (b Button) String() string {
+	switch b {
+	case Left:
+		return "left"
+	case Right:
+		return "right"
+	case Stage:
+		return "stage"
+	}
+	return "none"
+}
+
+func (b Button) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *Button) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "left":
+		*b = Left
+	case "right":
+		*b = Right
+	case "stage":
+		*b = Stage
+	default:
+		return fmt.Errorf("unknown button %q", text)
+	}
+	return nil
+}
+
+// Config holds the tunable numbers behind a launch.
+type Config struct {
+	SpeedMax      float64 // top speed in metres per 1/60s
+	PowerMax      float64 // fuel tank capacity
+	Gravity       float64 // terminal fall speed; also how hard the coast pulls
+	Thrust        float64 // speed gained per second of burn
+	BurnRate      float64 // fuel used per second of burn
+	CoastDecel    float64 // speed lost per second once the tank is dry
+	ComboTimeout  float64 // seconds allowed between alternating presses
+	BasePowerGain float64 // fuel per press
+	ComboBonus    float64 // extra fuel per combo step
+	CountFrom     int     // countdown start
+	Assists       Assists // assists in play; see ComboWindow
+	Scheme        Scheme  `json:",omitempty"` // how presses are made
+	Goal          Goal    `json:",omitzero"`  // what the launch is scored on
+	Beat          Beat    `json:",omitzero"`  // the music, in the Music scheme
+	Weather       Weather `json:",omitzero"`  // the sky flown through
+	Risks         Risks   `json:",omitzero"`  // what can make the launch fail
+	Rocket        Rocket  `json:",omitzero"`  // the parts rocket Apply built it for
+}
+
+// StandardGravity is the Normal preset's Gravity. A Config's coasting
+// deceleration is CoastDecel scaled by how its Gravity compares with this.
+const StandardGravity = -50
+
+// DefaultConfig returns the tuning the game has always shipped with, which
+// is the Normal preset.
+func DefaultConfig() Config {
+	return Config{
+		SpeedMax:      30,
+		PowerMax:      1200,
+		Gravity:       StandardGravity,
+		Thrust:        0.6,
+		BurnRate:      60,
+		CoastDecel:    2.4,
+		ComboTimeout:  0.35,
+		BasePowerGain: 5,
+		ComboBonus:    1.5,
+		CountFrom:     10,
+		Risks:         Risks{RedLine: 0.9, MaxSpeed: 12.5, StageWindow: 1.2},
+	}
+}
+
+// EventKind says what happened during a step.
+type EventKind int
+
+const (
+	// EventReady fires as each of Ready, Set and Go appears; Value is 1-3.
+	EventReady EventKind = iota
+	// EventChargeStart fires when the countdown begins; Value is its start.
+	EventChargeStart
+	// EventCount fires each time the countdown drops; Value is the new count.
+	EventCount
+	// EventTap fires for each accepted press; Value is the combo it reached.
+	EventTap
+	EventLaunch
+	// EventPowerDown fires when the tank runs dry in flight.
+	EventPowerDown
+	// EventZone fires on first entering a zone; Value indexes Zones.
+	EventZone
+	// EventLanded fires when the rocket is back on the ground and Result
+	// holds the run's statistics.
+	EventLanded
+	// EventGrade fires with EventTap in the Music scheme; Value is the
+	// press's Grade.
+	EventGrade
+	// EventBurnout fires when the booster is spent and the upper stage
+	// waits to be staged.
+	EventBurnout
+	// EventStage fires when the booster separates.
+	EventStage
+	// EventFailure fires when the launch fails; Value indexes Failures.
+	EventFailure
+)
+
+// Event is one thing the presentation may want to react to.
+type Event struct {
+	Kind  EventKind
+	Value int
+}
+
+// Flight is the state of one launch.
+type Flight struct {
+	Config
+	// Seed is the launch's seed, which decides the engine's roll in the
+	// red zone. Reset keeps it.
+	Seed uint64
+
+	Altitude     float64
+	Speed        float64
+	Power        float64
+	Best         float64 // highest altitude reached this run
+	PeakSpeed    float64
+	TotalFuel    float64
+	PrepDuration float64
+	RunDuration  float64
+	ComboTimer   float64
+	ComboCount   int
+	MaxCombo     int
+	TapCount     int
+	LastButton   Button
+
+	// Perfects, Goods and Misses count the grades of the Music scheme.
+	Perfects int
+	Goods    int
+	Misses   int
+
+	// Booster is the fuel left in the booster, part of Power, while the
+	// launch flies in two stages. StageWait counts the seconds since it
+	// burned out.
+	Booster   float64
+	Staged    bool
+	StageWait float64
+	// Failure is how the launch failed, if it has.
+	Failure Failure
+
+	Ready     int // Ready/Set/Go frames shown so far, 0-3
+	Count     int // countdown number on screen
+	Charging  bool
+	Launched  bool
+	PowerDown bool
+	Over      bool
+
+	ZoneReached int
+	Milestones  []ZoneMilestone
+	Result      ResultStats
+
+	// StepIndex counts steps since Reset.
+	StepIndex int
+
+	readyTimer float64
+	countTimer float64
+	events     []Event
+	// lastBeat is the beat last hit in the Music scheme; each beat can
+	// be hit once.
+	lastBeat    float64
+	beatClaimed bool
+	// engineDoomed is set at launch when the red zone's roll went against
+	// the engine; it fails once the fuel burns down to the red line.
+	engineDoomed bool
+}
+
+// New returns a flight waiting on the pad.
+func New(cfg Config) *Flight {
+	f := &Flight{Config: cfg}
+	f.Reset()
+	return f
+}
+
+// Reset puts the rocket back on the pad, keeping the configuration.
+func (f *Flight) Reset() {
+	*f = Flight{Config: f.Config, Seed: f.Seed, Count: f.CountFrom, events: f.events[:0]}
+}
+
+// CanCharge reports whether presses currently add fuel.
+func (f *Flight) CanCharge() bool {
+	return f.Ready >= 3 && !f.Launched && !f.Over
+}
+
+// Step advances the flight by dt seconds, applying presses first. The
+// returned slice is reused by the next call.
+func (f *Flight) Step(dt float64, presses []Button) []Event {
+	f.events = f.events[:0]
+	defer func() { f.StepIndex++ }()
+
+	f.updateCombo(dt)
+	if f.Over {
+		return f.events
+	}
+
+	if f.Ready < 3 {
+		f.readyTimer += dt
+		if f.readyTimer >= 1 {
+			f.Ready++
+			f.readyTimer = 0
+			f.emit(EventReady, f.Ready)
+		}
+	}
+
+	for _, b := range presses {
+		f.press(b)
+	}
+
+	if f.Ready >= 3 && !f.Launched {
+		if !f.Charging {
+			f.Charging = true
+			f.PrepDuration = 0
+			f.TapCount = 0
+			f.MaxCombo = 0
+			f.ComboCount = 0
+			f.ComboTimer = 0
+			f.LastButton = NoButton
+			f.TotalFuel = 0
+			f.Perfects, f.Goods, f.Misses = 0, 0, 0
+			f.beatClaimed = false
+			f.emit(EventChargeStart, f.Count)
+		}
+		f.PrepDuration += dt
+		if f.Count > 0 {
+			f.countTimer += dt
+			if f.countTimer >= 1 {
+				f.Count--
+				f.countTimer = 0
+				f.emit(EventCount, f.Count)
+			}
+		} else {
+			f.launch()
+		}
+	}
+
+	if f.Launched && f.Power <= 0 && !f.PowerDown {
+		f.PowerDown = true
+		f.emit(EventPowerDown, 0)
+	}
+
+	if f.Launched {
+		f.fly(dt)
+	}
+	return f.events
+}
+
+func (f *Flight) emit(kind EventKind, value int) {
+	f.events = append(f.events, Event{Kind: kind, Value: value})
+}
+
+// TapsLeft is how many presses the tap budget still allows, or -1 when
+// there is no budget.
+func (f *Flight) TapsLeft() int {
+	if f.Goal.Mode != Budget {
+		return -1
+	}
+	return max(0, f.Goal.Taps-f.TapCount)
+}
+
+func (f *Flight) press(b Button) {
+	if b == Stage {
+		f.stage()
+		return
+	}
+	if !f.CanCharge() || f.TapsLeft() == 0 {
+		return
+	}
+	if f.Scheme == Music {
+		f.pressOnBeat()
+		return
+	}
+	added := f.BasePowerGain
+	if f.LastButton != NoButton && f.LastButton != b && f.ComboTimer > 0 {
+		f.ComboCount++
+	} else {
+		f.ComboCount = 1
+	}
+	f.LastButton = b
+	f.ComboTimer = f.ComboWindow()
+	added += float64(float64(f.ComboCount-1) * f.ComboBonus)
+	f.Power += added
+	f.TotalFuel += added
+	if f.Power > f.PowerMax {
+		f.Power = f.PowerMax
+	}
+	f.TapCount++
+	if f.ComboCount > f.MaxCombo {
+		f.MaxCombo = f.ComboCount
+	}
+	f.emit(EventTap, f.ComboCount)
+}
+
+// pressOnBeat grades a press against the music. Hits are worth the fuel
+// of their grade and extend the streak to the next beat; a miss, or a
+// second press on a beat already hit, burns fuel and ends it.
+func (f *Flight) pressOnBeat() {
+	grade, at := f.Beat.GradeAt(float64(f.StepIndex) * StepDuration)
+	if grade != Miss && f.beatClaimed && math.Abs(at-f.lastBeat) < f.Beat.Period()/2 {
+		grade = Miss
+	}
+	var added float64
+	switch grade {
+	case Perfect:
+		added = f.BasePowerGain * PerfectGain
+		f.Perfects++
+	case Good:
+		added = f.BasePowerGain * GoodGain
+		f.Goods++
+	default:
+		added = -min(f.Power, f.BasePowerGain*MissLoss)
+		f.Misses++
+	}
+	if grade == Miss {
+		f.ComboCount = 0
+		f.ComboTimer = 0
+	} else {
+		if f.ComboTimer > 0 {
+			f.ComboCount++
+		} else {
+			f.ComboCount = 1
+		}
+		f.ComboTimer = f.ComboWindow()
+		f.lastBeat, f.beatClaimed = at, true
+	}
+	f.Power = min(f.Power+added, f.PowerMax)
+	f.TotalFuel += added
+	f.TapCount++
+	f.MaxCombo = max(f.MaxCombo, f.ComboCount)
+	f.emit(EventTap, f.ComboCount)
+	f.emit(EventGrade, int(grade))
+}
+
+func (f *Flight) updateCombo(dt float64) {
+	if f.ComboTimer > 0 {
+		f.ComboTimer -= dt
+		if f.ComboTimer <= 0 {
+			f.ComboTimer = 0
+			f.ComboCount = 0
+			f.LastButton = NoButton
+		}
+	}
+}
+
+func (f *Flight) launch() {
+	f.Launched = true
+	f.ZoneReached = 0
+	f.Milestones = nil
+	f.PowerDown = false
+	f.RunDuration = 0
+	f.PeakSpeed = 0
+	f.ComboCount = 0
+	f.ComboTimer = 0
+	f.Booster, f.Staged, f.StageWait = 0, false, 0
+	if f.Risks.StageWindow > 0 {
+		f.Booster = f.Power * BoosterShare
+	}
+	f.Failure = NoFailure
+	f.engineDoomed = f.rollEngine()
+	f.emit(EventLaunch, 0)
+}
+
+// fly moves the rocket on by dt. Every product added to the state is
+// converted to float64 so no platform fuses it into a multiply-add, which
+// rounds differently: a replay must fly the same on every machine that
+// verifies it.
+func (f *Flight) fly(dt float64) {
+	f.RunDuration += dt
+	gravity := f.Weather.GravityScale()
+	if f.StagePending() {
+		if f.StageWait += dt; f.StageWait > f.Risks.StageWindow {
+			f.fail(StagingFailure)
+		}
+	}
+	if f.Speed < f.SpeedMax {
+		if f.Power > 0 && !f.StagePending() {
+			f.Speed += float64(f.Thrust * dt)
+			f.Power -= float64(f.BurnRate * dt)
+			if f.Power < 0 {
+				f.Power = 0
+			}
+			if f.Booster > 0 {
+				if f.Booster -= float64(f.BurnRate * dt); f.Booster <= 0 {
+					f.Booster = 0
+					f.emit(EventBurnout, 0)
+				}
+			}
+		} else if f.Speed > f.Gravity*gravity && f.Altitude >= 0 {
+			f.Speed -= float64(f.CoastDecel * f.Gravity / StandardGravity * gravity * dt)
+		}
+	}
+	if f.Speed > 0 {
+		f.Speed -= float64(f.Speed * f.Weather.Drag() * dt)
+	}
+	if f.Speed > f.PeakSpeed {
+		f.PeakSpeed = f.Speed
+	}
+	if f.Failure == NoFailure {
+		switch {
+		case f.engineDoomed && f.Power <= f.RedLineFuel():
+			f.fail(EngineFailure)
+		case f.Risks.MaxSpeed > 0 && f.Speed > f.Risks.MaxSpeed:
+			f.fail(StructuralFailure)
+		}
+	}
+	if f.Altitude >= 0 {
+		f.Altitude += float64(f.Speed * speedUnit * dt)
+	}
+	if f.Altitude < 0 {
+		f.Altitude = 0
+	}
+	if f.Altitude > f.Best {
+		f.Best = f.Altitude
+	}
+
+	for z := ZoneIndexAt(f.Altitude); f.ZoneReached < z; {
+		f.ZoneReached++
+		f.Milestones = append(f.Milestones, ZoneMilestone{
+			Zone:  Zones[f.ZoneReached].Name,
+			Time:  f.RunDuration,
+			Speed: f.Speed,
+		})
+		f.emit(EventZone, f.ZoneReached)
+	}
+
+	if f.PowerDown && f.Altitude <= 0 {
+		f.land()
+	}
+}
+
+// Land ends the flight where it is, as if the rocket had come down, and
+// returns the events that makes. It is for debugging; launches land by
+// themselves.
+func (f *Flight) Land() []Event {
+	f.events = f.events[:0]
+	if !f.Over {
+		f.land()
+	}
+	return f.events
+}
+
+func (f *Flight) land() {
+	averageTPS := 0.0
+	if f.PrepDuration > 0 {
+		averageTPS = float64(f.TapCount) / f.PrepDuration
+	}
+	f.Result = ResultStats{
+		Altitude:      f.Best,
+		PeakSpeed:     f.PeakSpeed,
+		Duration:      f.RunDuration,
+		PrepDuration:  f.PrepDuration,
+		TapCount:      f.TapCount,
+		MaxCombo:      f.MaxCombo,
+		Perfects:      f.Perfects,
+		Goods:         f.Goods,
+		Misses:        f.Misses,
+		AverageTPS:    averageTPS,
+		FuelCollected: f.TotalFuel,
+		HighestZone:   Zones[f.ZoneReached].Name,
+		Milestones:    f.Milestones,
+		Category:      f.Config.Category(),
+		Weather:       f.Weather,
+		Failure:       f.Failure,
+	}
+	if f.Goal.Mode != Classic || f.Failure != NoFailure {
+		o := f.Goal.Evaluate(f.Result)
+		// A launch that failed met no goal, however high it got.
+		o.Missed = o.Missed || f.Failure != NoFailure
+		f.Result.Outcome = &o
+	}
+	f.Altitude = 0
+	f.Speed = 0
+	f.Launched = false
+	f.PowerDown = false
+	f.Power = 0
+	f.Over = true
+	f.Charging = false
+	f.ComboCount = 0
+	f.ComboTimer = 0
+	f.emit(EventLanded, 0)
+}
@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mode is what a launch is scored on. Each mode keeps its own records.
+type Mode string
+
+const (
+	// Classic is going as high as possible, the original game.
+	Classic Mode = ""
+	// Target is reaching Goal.Altitude in as few taps as possible.
+	Target Mode = "target"
+	// Precision is topping out within Goal.Tolerance of Goal.Altitude;
+	// the closer the apogee to the middle of the band, the better.
+	Precision Mode = "precision"
+	// Budget is going as high as possible on at most Goal.Taps presses.
+	Budget Mode = "budget"
+)
+
+// Modes lists every mode in menu order.
+var Modes = []Mode{Classic, Target, Precision, Budget}
+
+func (m Mode) String() string {
+	if m == Classic {
+		return "classic"
+	}
+	return string(m)
+}
+
+// ParseMode accepts a mode name in any case; "classic", "launch" and the
+// empty string all mean Classic.
+func ParseMode(name string) (Mode, error) {
+	name = strings.ToLower(name)
+	if name == "launch" {
+		return Classic, nil
+	}
+	for _, m := range Modes {
+		if name == m.String() || name == string(m) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown mode %q, want one of %v", name, Modes)
+}
+
+// LowerIsBetter reports whether the mode's score is a cost to keep down
+// rather than a height to push up.
+func (m Mode) LowerIsBetter() bool {
+	return m == Target || m == Precision
+}
+
+// Goal is the mode a launch is played in and the numbers it is judged by.
+type Goal struct {
+	Mode      Mode    `json:",omitempty"`
+	Altitude  float64 `json:",omitempty"` // Target and Precision, in metres
+	Tolerance float64 `json:",omitempty"` // Precision: half the band's width
+	Taps      int     `json:",omitempty"` // Budget: presses allowed
+}
+
+// DefaultGoal returns the standard goal for m.
+func DefaultGoal(m Mode) Goal {
+	switch m {
+	case Target:
+		return Goal{Mode: Target, Altitude: 5000}
+	case Precision:
+		return Goal{Mode: Precision, Altitude: 4000, Tolerance: 150}
+	case Budget:
+		return Goal{Mode: Budget, Taps: 60}
+	}
+	return Goal{}
+}
+
+// Check reports goals that could never be met or scored.
+func (g Goal) Check() error {
+	switch g.Mode {
+	case Classic:
+	case Target:
+		if g.Altitude <= 0 {
+			return fmt.Errorf("target altitude %g must be positive", g.Altitude)
+		}
+	case Precision:
+		if g.Altitude <= 0 || g.Tolerance <= 0 {
+			return fmt.Errorf("precision band %g±%g must be positive", g.Altitude, g.Tolerance)
+		}
+	case Budget:
+		if g.Taps <= 0 {
+			return fmt.Errorf("tap budget %d must be positive", g.Taps)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q", g.Mode)
+	}
+	return nil
+}
+
+// Key is the goal's part of a score category, such as "target-5000". Goals
+// with different numbers are ranked apart.
+func (g Goal) Key() string {
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	switch g.Mode {
+	case Target:
+		return "target-" + num(g.Altitude)
+	case Precision:
+		return "precision-" + num(g.Altitude) + "-" + num(g.Tolerance)
+	case Budget:
+		return "budget-" + strconv.Itoa(g.Taps)
+	}
+	return ""
+}
+
+// ParseGoalKey reverses Key.
+func ParseGoalKey(key string) (Goal, error) {
+	if key == "" {
+		return Goal{}, nil
+	}
+	parts := strings.Split(key, "-")
+	m, err := ParseMode(parts[0])
+	if err != nil {
+		return Goal{}, err
+	}
+	g := Goal{Mode: m}
+	nums := make([]float64, len(parts)-1)
+	for i, p := range parts[1:] {
+		if nums[i], err = strconv.ParseFloat(p, 64); err != nil {
+			return Goal{}, fmt.Errorf("goal %q: %w", key, err)
+		}
+	}
+	want := map[Mode]int{Target: 1, Precision: 2, Budget: 1}[m]
+	if len(nums) != want {
+		return Goal{}, fmt.Errorf("goal %q: want %d numbers", key, want)
+	}
+	switch m {
+	case Target:
+		g.Altitude = nums[0]
+	case Precision:
+		g.Altitude, g.Tolerance = nums[0], nums[1]
+	case Budget:
+		g.Taps = int(nums[0])
+	}
+	return g, nil
+}
+
+// GoalOf returns the goal encoded in a score category.
+func GoalOf(category string) Goal {
+	_, key, _ := strings.Cut(category, ":")
+	g, _ := ParseGoalKey(key)
+	return g
+}
+
+// Outcome is how a launch did against its goal.
+type Outcome struct {
+	// Missed is set when a Target or Precision launch failed its goal,
+	// and for any launch that failed outright.
+	Missed bool `json:",omitempty"`
+	// Score is the altitude in Classic and Budget, the taps used in Target
+	// and the distance from the middle of the band in Precision.
+	Score float64
+}
+
+// Evaluate judges a finished launch against the goal.
+func (g Goal) Evaluate(r ResultStats) Outcome {
+	switch g.Mode {
+	case Target:
+		return Outcome{Missed: r.Altitude < g.Altitude, Score: float64(r.TapCount)}
+	case Precision:
+		miss := math.Abs(r.Altitude - g.Altitude)
+		return Outcome{Missed: miss > g.Tolerance, Score: miss}
+	}
+	return Outcome{Score: r.Altitude}
+}
+
+// Beats reports whether a is a better outcome than b in mode m. A missed
+// goal never beats one that was met.
+func (m Mode) Beats(a, b Outcome) bool {
+	if a.Missed != b.Missed {
+		return !a.Missed
+	}
+	if m.LowerIsBetter() {
+		return a.Score < b.Score
+	}
+	return a.Score > b.Score
+}
@@ -0,0 +1,157 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ReplayVersion is the replay format this package reads and writes.
+const ReplayVersion = 2
+
+// Replay is everything needed to re-run a launch: the seed and tuning it
+// started from and every charge press, stamped with the step it was
+// applied on.
+type Replay struct {
+	Version  int          `json:"version"`
+	Seed     uint64       `json:"seed"`
+	Rate     int          `json:"rate"`
+	Config   Config       `json:"config"`
+	Recorded time.Time    `json:"recorded"`
+	Taps     []Tap        `json:"taps"`
+	Result   *ResultStats `json:"result,omitempty"`
+	// Loadout is the rocket the launch was flown in, so a replay or a
+	// ghost looks the way it did to its player.
+	Loadout Loadout `json:"loadout,omitzero"`
+}
+
+// Loadout is the rocket's parts: the id of the part in each hangar slot,
+// empty for the slot's stock part. What the parts do to the flight is
+// already in the Config they were applied to, so a replay's loadout only
+// decides how it looks.
+type Loadout struct {
+	Body    string `json:"body,omitempty"`
+	Nose    string `json:"nose,omitempty"`
+	Fins    string `json:"fins,omitempty"`
+	Engine  string `json:"engine,omitempty"`
+	Tank    string `json:"tank,omitempty"`
+	Paint   string `json:"paint,omitempty"`
+	Exhaust string `json:"exhaust,omitempty"`
+}
+
+// Tap is one charge press.
+type Tap struct {
+	Step   int    `json:"step"`
+	Button Button `json:"button"`
+}
+
+// NewReplay starts an empty recording.
+func NewReplay(seed uint64, cfg Config) *Replay {
+	return &Replay{Version: ReplayVersion, Seed: seed, Rate: Rate, Config: cfg}
+}
+
+// Record appends the presses applied on step.
+func (r *Replay) Record(step int, presses []Button) {
+	for _, b := range presses {
+		r.Taps = append(r.Taps, Tap{Step: step, Button: b})
+	}
+}
+
+// LoadReplay reads a replay file and checks this build can reproduce it.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse replay %s: %w", path, err)
+	}
+	if err := r.Check(); err != nil {
+		return nil, fmt.Errorf("replay %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// Check reports whether this build can reproduce r.
+func (r *Replay) Check() error {
+	if r.Version != ReplayVersion {
+		return fmt.Errorf("unsupported version %d", r.Version)
+	}
+	if r.Rate != Rate {
+		return fmt.Errorf("recorded at %d steps/s, this build simulates at %d", r.Rate, Rate)
+	}
+	return nil
+}
+
+// Save writes the replay to path.
+func (r *Replay) Save(path string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Input supplies the charge presses for the flight's next step. The
+// keyboard, replays and bots all drive a Flight through it.
+type Input interface {
+	Presses(f *Flight) []Button
+}
+
+// Playback is an Input that feeds a replay's presses back step by step.
+type Playback struct {
+	Replay *Replay
+	pos    int
+	buf    []Button
+}
+
+// NewPlayback plays r from its first step.
+func NewPlayback(r *Replay) *Playback {
+	return &Playback{Replay: r}
+}
+
+// Rewind starts the playback over.
+func (p *Playback) Rewind() {
+	p.pos = 0
+}
+
+func (p *Playback) Presses(f *Flight) []Button {
+	p.buf = p.buf[:0]
+	taps := p.Replay.Taps
+	for p.pos < len(taps) && taps[p.pos].Step <= f.StepIndex {
+		if taps[p.pos].Step == f.StepIndex {
+			p.buf = append(p.buf, taps[p.pos].Button)
+		}
+		p.pos++
+	}
+	return p.buf
+}
+
+// MaxSteps caps headless runs so a malformed input cannot spin forever;
+// no real launch lasts ten minutes.
+const MaxSteps = 10 * 60 * Rate
+
+// Run drives a fresh flight on seed with in until it lands and returns its
+// result. When rec is non-nil every applied press is recorded into it.
+func Run(cfg Config, seed uint64, in Input, rec *Replay) (ResultStats, error) {
+	f := New(cfg)
+	f.Seed = seed
+	for !f.Over {
+		if f.StepIndex >= MaxSteps {
+			return ResultStats{}, fmt.Errorf("flight did not land within %d steps", MaxSteps)
+		}
+		presses := in.Presses(f)
+		if rec != nil {
+			rec.Record(f.StepIndex, presses)
+		}
+		f.Step(StepDuration, presses)
+	}
+	return f.Result, nil
+}
+
+// Simulate re-runs a replay headlessly.
+func Simulate(r *Replay) (ResultStats, error) {
+	return Run(r.Config, r.Seed, NewPlayback(r), nil)
+}
@@ -0,0 +1,83 @@
+package sim
+
+// ResultStats summarises a finished launch.
+type ResultStats struct {
+	Altitude     float64
+	PeakSpeed    float64
+	Duration     float64
+	PrepDuration float64
+	TapCount     int
+	MaxCombo     int
+	// Perfects, Goods and Misses grade the presses of a Music launch.
+	Perfects      int `json:",omitempty"`
+	Goods         int `json:",omitempty"`
+	Misses        int `json:",omitempty"`
+	AverageTPS    float64
+	FuelCollected float64
+	HighestZone   string
+	Milestones    []ZoneMilestone
+	// Category is the difficulty the run was played on; see
+	// Config.Category. Runs saved before difficulties existed have none
+	// and were played on Normal.
+	Category string `json:",omitempty"`
+	// Outcome is how the run did against its goal, for every mode but
+	// Classic.
+	Outcome *Outcome `json:",omitempty"`
+	// Weather is the sky the run was flown in; runs before weather were
+	// all flown in clear skies.
+	Weather Weather `json:",omitzero"`
+	// Failure is how the launch failed, if it did; a failed launch has
+	// missed whatever goal it had.
+	Failure Failure `json:",omitempty"`
+}
+
+// CategoryOrNormal returns r.Category, or "normal" for older runs.
+func (r ResultStats) CategoryOrNormal() string {
+	if r.Category == "" {
+		return string(Normal)
+	}
+	return r.Category
+}
+
+// OutcomeOrClassic returns r.Outcome, or the Classic outcome of scoring
+// the altitude.
+func (r ResultStats) OutcomeOrClassic() Outcome {
+	if r.Outcome != nil {
+		return *r.Outcome
+	}
+	return Outcome{Score: r.Altitude}
+}
+
+// Zone is one altitude band of the sky. Floor is the altitude in metres at
+// which the band starts; a zone ends where the next one begins.
+type Zone struct {
+	Name  string
+	Floor float64
+}
+
+// Zones lists the bands from the ground up.
+var Zones = []Zone{
+	{Name: "Troposphere", Floor: 0},
+	{Name: "Jet Stream", Floor: 2500},
+	{Name: "Stratosphere", Floor: 4500},
+	{Name: "Mesosphere", Floor: 8000},
+	{Name: "Karman Line", Floor: 11000},
+	{Name: "Orbit", Floor: 12500},
+}
+
+// ZoneIndexAt returns the index into Zones of the band containing altitude.
+func ZoneIndexAt(altitude float64) int {
+	for i := len(Zones) - 1; i > 0; i-- {
+		if altitude >= Zones[i].Floor {
+			return i
+		}
+	}
+	return 0
+}
+
+// ZoneMilestone records the moment a run first crossed into a zone.
+type ZoneMilestone struct {
+	Zone  string
+	Time  float64
+	Speed float64
+}
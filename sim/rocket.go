@@ -0,0 +1,111 @@
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Rocket is a rocket assembled from parts, as the totals over them. The
+// presets are tuned for StockRocket; Apply turns them into the tuning of
+// any other.
+type Rocket struct {
+	Mass   float64 `json:"mass"`   // dry mass in tonnes
+	Thrust float64 `json:"thrust"` // engine thrust in tonnes-force
+	Fuel   float64 `json:"fuel"`   // tank capacity in units of fuel
+	Drag   float64 `json:"drag"`   // 1 for the stock nose and tail
+}
+
+// FuelMass is what one unit of fuel weighs, in tonnes.
+const FuelMass = 0.03
+
+// StockRocket is the rocket the game has always flown.
+var StockRocket = Rocket{Mass: 48, Thrust: 126, Fuel: 1200, Drag: 1}
+
+// TWR is the thrust-to-weight ratio on the pad with a full tank. At 1 or
+// below the engine cannot lift the rocket.
+func (r Rocket) TWR() float64 {
+	weight := r.Mass + float64(r.Fuel*FuelMass)
+	if weight <= 0 {
+		return 0
+	}
+	return r.Thrust / weight
+}
+
+// ErrGrounded is returned for a rocket too heavy for its engine.
+var ErrGrounded = errors.New("thrust-to-weight is not above 1, the rocket cannot lift off")
+
+// Check reports whether r can fly.
+func (r Rocket) Check() error {
+	switch {
+	case r.Fuel <= 0:
+		return errors.New("the rocket has no fuel tank")
+	case r.Drag <= 0:
+		return fmt.Errorf("drag %g must be positive", r.Drag)
+	case r.TWR() <= 1:
+		return fmt.Errorf("%w: %.2f", ErrGrounded, r.TWR())
+	}
+	return nil
+}
+
+// Apply returns c retuned for r, which must pass Check. The tank sets the
+// fuel capacity; thrust-to-weight sets the acceleration, counting only
+// the thrust left over once the weight is held up; a bigger engine burns
+// fuel faster; and drag lowers the top speed and, for half its share,
+// slows the coast. c keeps r as its Rocket, so the launch is ranked as
+// built from parts. The stock rocket, or none, leaves c as it is, so its
+// launches stay on their preset.
+func (r Rocket) Apply(c Config) Config {
+	s := StockRocket
+	if r.stock() {
+		return c
+	}
+	c.Rocket = r
+	c.PowerMax *= r.Fuel / s.Fuel
+	c.Thrust *= (r.TWR() - 1) / (s.TWR() - 1)
+	c.BurnRate *= r.Thrust / s.Thrust
+	c.SpeedMax *= s.Drag / r.Drag
+	c.CoastDecel *= (1 + r.Drag/s.Drag) / 2
+	c.Risks.MaxSpeed *= math.Sqrt(s.Drag / r.Drag)
+	return c
+}
+
+// stock reports whether r is the stock rocket, or no rocket at all.
+func (r Rocket) stock() bool {
+	return r == StockRocket || r == (Rocket{})
+}
+
+// Ceiling is how high c's rocket climbs with fuel in the tank, in still
+// air, staged without delay and with an engine that never fails. An
+// airframe pushed past its speed limit still breaks up, which failure
+// reports.
+func (c Config) Ceiling(fuel float64) (altitude float64, failure Failure) {
+	c.Weather = Weather{}
+	c.Risks.RedLine = 0
+	c.Risks.StageWindow = 0
+	f := New(c)
+	f.Ready = 3
+	f.Power = min(fuel, f.PowerMax)
+	f.launch()
+	for !f.Over && f.StepIndex < MaxSteps {
+		f.Step(StepDuration, nil)
+	}
+	return f.Best, f.Failure
+}
+
+// ceilingSteps is how finely BestCharge tries tank levels.
+const ceilingSteps = 50
+
+// BestCharge finds the charge, as a fraction of the tank, that climbs
+// highest without breaking up, and that height. A rocket quick enough to
+// overspeed does best with a tank part full.
+func (c Config) BestCharge() (charge, altitude float64) {
+	for i := 1; i <= ceilingSteps; i++ {
+		frac := float64(i) / ceilingSteps
+		alt, failure := c.Ceiling(frac * c.PowerMax)
+		if failure == NoFailure && alt > altitude {
+			charge, altitude = frac, alt
+		}
+	}
+	return charge, altitude
+}
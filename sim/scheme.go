@@ -0,0 +1,183 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Scheme is how the player produces charge presses. Every scheme feeds the
+// same fuel and combo rules, but each has its own best possible cadence,
+// so scores are ranked separately per scheme.
+type Scheme string
+
+const (
+	// Alternate is mashing two buttons in turn, the original controls.
+	Alternate Scheme = ""
+	// Rhythm is a single button tapped on the beat of a metronome; each
+	// tap on a fresh beat continues the combo.
+	Rhythm Scheme = "rhythm"
+	// HoldRelease is holding a button while a meter fills and letting go
+	// inside its target window; the closer, the more presses it is worth.
+	HoldRelease Scheme = "hold"
+	// Scanning is a single switch: the highlight steps between the two
+	// charge buttons and the switch presses whichever is lit.
+	Scanning Scheme = "scan"
+	// Music is tapping along to the game's music: each press is graded
+	// against the track's beat map and fuelled by its grade alone.
+	Music Scheme = "music"
+)
+
+// Schemes lists every scheme in menu order.
+var Schemes = []Scheme{Alternate, Rhythm, HoldRelease, Scanning, Music}
+
+// Timing of the one-button schemes, in seconds.
+const (
+	RhythmBeat   = 0.3
+	RhythmWindow = 0.08 // either side of the beat
+	HoldCycle    = 0.8  // meter empty to full
+	HoldBurstGap = 0.1  // between the presses a release is worth
+	ScanStep     = 0.45
+)
+
+func (s Scheme) String() string {
+	if s == Alternate {
+		return "alternate"
+	}
+	return string(s)
+}
+
+// ParseScheme accepts a scheme name in any case; "alternate" and the empty
+// string both mean Alternate.
+func ParseScheme(name string) (Scheme, error) {
+	name = strings.ToLower(name)
+	for _, s := range Schemes {
+		if name == s.String() || name == string(s) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown charge scheme %q, want one of %v", name, Schemes)
+}
+
+// minComboWindow is the narrowest combo window in which the scheme's best
+// cadence can keep a combo going.
+func (s Scheme) minComboWindow() float64 {
+	switch s {
+	case Rhythm:
+		return RhythmBeat + RhythmWindow
+	case HoldRelease:
+		return HoldBurstGap * 1.5
+	case Scanning:
+		return ScanStep * 1.5
+	}
+	return 0
+}
+
+// cadence follows a replay's presses through its flight and flags the
+// first charge press the scheme's controls could not have made: an
+// alternation off the metronome's beat, a press on the unlit button or
+// twice in one scanning step, or a hold burst out of turn.
+type cadence struct {
+	in     Input
+	scheme Scheme
+	last   Button
+	// lastBeat is the metronome beat or scanning step last pressed on.
+	lastBeat int
+	first    string
+	broken   int
+}
+
+func newCadence(s Scheme, in Input) *cadence {
+	return &cadence{in: in, scheme: s, lastBeat: -1}
+}
+
+func (c *cadence) Presses(f *Flight) []Button {
+	presses := c.in.Presses(f)
+	for _, b := range presses {
+		if b == Stage {
+			continue
+		}
+		if why := c.check(f.PrepDuration, b); why != "" {
+			if c.broken == 0 {
+				c.first = fmt.Sprintf("step %d: %s %s", f.StepIndex, b, why)
+			}
+			c.broken++
+		}
+	}
+	return presses
+}
+
+// check reports why a press of b, t seconds into charging, breaks the
+// scheme, or "" if it does not. The game's scheme handlers read the same
+// t when they make their presses.
+func (c *cadence) check(t float64, b Button) string {
+	switch c.scheme {
+	case Rhythm:
+		want := c.last
+		if want == NoButton {
+			want = Left
+		}
+		beat := int(math.Round(t / RhythmBeat))
+		if math.Abs(t-float64(beat)*RhythmBeat) <= RhythmWindow && beat != c.lastBeat {
+			want = otherButton(c.last)
+			c.lastBeat = beat
+		}
+		c.last = b
+		if b != want {
+			return "pressed off the beat"
+		}
+	case HoldRelease:
+		want := otherButton(c.last)
+		c.last = b
+		if b != want {
+			return "pressed out of turn"
+		}
+	case Scanning:
+		step := int(t / ScanStep)
+		lit := Left
+		if step%2 == 1 {
+			lit = Right
+		}
+		if step == c.lastBeat {
+			return "pressed twice in one scanning step"
+		}
+		c.lastBeat = step
+		if b != lit {
+			return "pressed while unlit"
+		}
+	}
+	return ""
+}
+
+// flags lists the first broken press and how many there were.
+func (c *cadence) flags() []string {
+	switch c.broken {
+	case 0:
+		return nil
+	case 1:
+		return []string{c.first}
+	}
+	return []string{fmt.Sprintf("%s, and %d more presses off the %s cadence", c.first, c.broken-1, c.scheme)}
+}
+
+// manualTaps drops the presses the AutoTap assist made: those that come
+// exactly AutoTapRate's interval after the press before and alternate
+// with it. What is left is the player's own pressing.
+func manualTaps(taps []Tap) []Tap {
+	const interval = Rate / AutoTapRate
+	out := make([]Tap, 0, len(taps))
+	for i, t := range taps {
+		if i > 0 && t.Step == taps[i-1].Step+interval && taps[i-1].Button != Stage && t.Button == otherButton(taps[i-1].Button) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func otherButton(b Button) Button {
+	if b == Left {
+		return Right
+	}
+	return Left
+}
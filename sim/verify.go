@@ -0,0 +1,184 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+)
+
+// Limits are the thresholds beyond which input timing is judged not to
+// have come from a person.
+type Limits struct {
+	// MaxSustainedTPS is the highest tap rate accepted over Window seconds.
+	MaxSustainedTPS float64
+	Window          float64
+	// MaxTapsPerStep bounds presses landing on a single step; with two
+	// buttons a person can manage at most one each.
+	MaxTapsPerStep int
+	// MinIntervalCV is the lowest accepted coefficient of variation of the
+	// gaps between taps. Metronomic input scores near zero.
+	MinIntervalCV float64
+	// MaxIdenticalRun is the longest accepted run of equal gaps in a row.
+	MaxIdenticalRun int
+	// MinTapsForTiming is how many taps a run needs before the interval
+	// checks apply.
+	MinTapsForTiming int
+}
+
+// DefaultLimits are generous enough for the fastest human mashers.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSustainedTPS:  20,
+		Window:           2,
+		MaxTapsPerStep:   2,
+		MinIntervalCV:    0.04,
+		MaxIdenticalRun:  15,
+		MinTapsForTiming: 20,
+	}
+}
+
+// Report is the outcome of verifying a claimed result against its replay.
+type Report struct {
+	Claimed    ResultStats
+	Reproduced ResultStats
+	// Mismatches lists every claimed statistic the replay did not reproduce.
+	Mismatches []string
+	// Flags lists every way the input timing looked implausible.
+	Flags []string
+}
+
+// OK reports whether the claim was reproduced and the input looked human.
+func (r Report) OK() bool {
+	return len(r.Mismatches) == 0 && len(r.Flags) == 0
+}
+
+// Verify re-simulates r headlessly and checks that claimed is exactly what
+// it produces, then inspects the tap timing against limits and the cadence
+// of the replay's charge scheme.
+func Verify(r *Replay, claimed ResultStats, limits Limits) (Report, error) {
+	cad := newCadence(r.Config.Scheme, NewPlayback(r))
+	got, err := Run(r.Config, r.Seed, cad, nil)
+	if err != nil {
+		return Report{}, err
+	}
+	rep := Report{Claimed: claimed, Reproduced: got}
+	mismatch := func(name string, want, have any) {
+		rep.Mismatches = append(rep.Mismatches, fmt.Sprintf("%s: claimed %v, replay gives %v", name, want, have))
+	}
+	if claimed.Altitude != got.Altitude {
+		mismatch("altitude", claimed.Altitude, got.Altitude)
+	}
+	if claimed.TapCount != got.TapCount {
+		mismatch("taps", claimed.TapCount, got.TapCount)
+	}
+	if claimed.AverageTPS != got.AverageTPS {
+		mismatch("TPS", claimed.AverageTPS, got.AverageTPS)
+	}
+	if claimed.MaxCombo != got.MaxCombo {
+		mismatch("max combo", claimed.MaxCombo, got.MaxCombo)
+	}
+	if claimed.Perfects != got.Perfects || claimed.Goods != got.Goods || claimed.Misses != got.Misses {
+		grades := func(r ResultStats) string { return fmt.Sprintf("%d/%d/%d", r.Perfects, r.Goods, r.Misses) }
+		mismatch("grades", grades(claimed), grades(got))
+	}
+	if claimed.OutcomeOrClassic() != got.OutcomeOrClassic() {
+		mismatch("outcome", claimed.OutcomeOrClassic(), got.OutcomeOrClassic())
+	}
+	if claimed.Failure != got.Failure {
+		mismatch("failure", claimed.Failure, got.Failure)
+	}
+	if claimed.Weather != got.Weather {
+		mismatch("weather", claimed.Weather, got.Weather)
+	}
+	if claimed.CategoryOrNormal() != got.Category {
+		mismatch("category", claimed.CategoryOrNormal(), got.Category)
+	}
+	timed := r.Taps
+	switch {
+	case r.Config.Scheme != Alternate:
+		// The one-button schemes press on a beat by design, which the
+		// cadence check holds them to; only the rate checks still mean
+		// anything.
+		limits.MinIntervalCV = 0
+		limits.MaxIdenticalRun = math.MaxInt
+	case r.Config.Assists.AutoTap:
+		// A held button presses at a steady rate by design; the rest must
+		// still look like a person's.
+		timed = manualTaps(r.Taps)
+	}
+	rep.Flags = append(CheckTiming(timed, limits), cad.flags()...)
+	return rep, nil
+}
+
+// CheckTiming looks for tap patterns beyond human ability: too many
+// presses on one step, sustained rates above the limit, and intervals so
+// regular they must have been generated.
+func CheckTiming(taps []Tap, limits Limits) []string {
+	var flags []string
+
+	perStep := 0
+	for i := range taps {
+		if i > 0 && taps[i].Step == taps[i-1].Step {
+			perStep++
+			if taps[i].Button == taps[i-1].Button {
+				flags = append(flags, fmt.Sprintf("step %d: %s pressed twice on one step", taps[i].Step, taps[i].Button))
+			}
+		} else {
+			perStep = 1
+		}
+		if perStep == limits.MaxTapsPerStep+1 {
+			flags = append(flags, fmt.Sprintf("step %d: more than %d presses on one step", taps[i].Step, limits.MaxTapsPerStep))
+		}
+	}
+
+	window := int(math.Round(limits.Window * Rate))
+	if window > 0 {
+		worst, at := 0, 0
+		for lo, hi := 0, 0; hi < len(taps); hi++ {
+			for taps[hi].Step-taps[lo].Step >= window {
+				lo++
+			}
+			if n := hi - lo + 1; n > worst {
+				worst, at = n, taps[lo].Step
+			}
+		}
+		if rate := float64(worst) / limits.Window; rate > limits.MaxSustainedTPS {
+			flags = append(flags, fmt.Sprintf("%.1f taps/s sustained over %.1fs from step %d exceeds %.1f", rate, limits.Window, at, limits.MaxSustainedTPS))
+		}
+	}
+
+	if len(taps) < limits.MinTapsForTiming {
+		return flags
+	}
+	gaps := make([]float64, 0, len(taps)-1)
+	for i := 1; i < len(taps); i++ {
+		gaps = append(gaps, float64(taps[i].Step-taps[i-1].Step))
+	}
+	var mean, variance float64
+	for _, g := range gaps {
+		mean += g
+	}
+	mean /= float64(len(gaps))
+	for _, g := range gaps {
+		variance += float64((g - mean) * (g - mean))
+	}
+	variance /= float64(len(gaps))
+	if mean > 0 {
+		if cv := math.Sqrt(variance) / mean; cv < limits.MinIntervalCV {
+			flags = append(flags, fmt.Sprintf("tap intervals vary by %.1f%%, below the %.1f%% expected of a person", cv*100, limits.MinIntervalCV*100))
+		}
+	}
+
+	run, longest := 1, 1
+	for i := 1; i < len(gaps); i++ {
+		if gaps[i] == gaps[i-1] {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 1
+		}
+	}
+	if longest > limits.MaxIdenticalRun {
+		flags = append(flags, fmt.Sprintf("%d identical tap intervals in a row, more than %d", longest, limits.MaxIdenticalRun))
+	}
+	return flags
+}
@@ -0,0 +1,249 @@
+package sim
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// masher alternates the charge buttons with gaps cycling through gaps, in
+// steps, and stages as soon as the booster is spent.
+type masher struct {
+	gaps []int
+	next int
+	i    int
+	last Button
+}
+
+func (m *masher) Presses(f *Flight) []Button {
+	if f.StagePending() {
+		return []Button{Stage}
+	}
+	if !f.CanCharge() || f.StepIndex < m.next {
+		return nil
+	}
+	m.next = f.StepIndex + m.gaps[m.i%len(m.gaps)]
+	m.i++
+	m.last = otherButton(m.last)
+	return []Button{m.last}
+}
+
+// record flies cfg with a human-paced masher and returns the replay, its
+// result filled in.
+func record(t *testing.T, cfg Config) *Replay {
+	t.Helper()
+	r := NewReplay(1, cfg)
+	res, err := Run(cfg, r.Seed, &masher{gaps: []int{5, 7, 4, 6, 8, 5}}, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Result = &res
+	return r
+}
+
+func TestVerifyReproduces(t *testing.T) {
+	r := record(t, Preset(Normal))
+	rep, err := Verify(r, *r.Result, DefaultLimits())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.OK() {
+		t.Fatalf("honest replay refused: %v %v", rep.Mismatches, rep.Flags)
+	}
+}
+
+func TestVerifyMismatches(t *testing.T) {
+	r := record(t, Preset(Normal))
+	tests := []struct {
+		name   string
+		forge  func(*ResultStats)
+		expect string
+	}{
+		{"altitude", func(c *ResultStats) { c.Altitude += 1 }, "altitude"},
+		{"taps", func(c *ResultStats) { c.TapCount++ }, "taps"},
+		{"tps", func(c *ResultStats) { c.AverageTPS *= 1.1 }, "TPS"},
+		{"combo", func(c *ResultStats) { c.MaxCombo += 5 }, "max combo"},
+		{"grades", func(c *ResultStats) { c.Perfects = 3 }, "grades"},
+		{"failure", func(c *ResultStats) { c.Failure = EngineFailure }, "failure"},
+		{"weather", func(c *ResultStats) { c.Weather = Weather{Kind: Storm, Wind: 20} }, "weather"},
+		{"category", func(c *ResultStats) { c.Category = string(Easy) }, "category"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claimed := *r.Result
+			tt.forge(&claimed)
+			rep, err := Verify(r, claimed, DefaultLimits())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rep.Mismatches) == 0 || !strings.HasPrefix(rep.Mismatches[0], tt.expect+":") {
+				t.Errorf("mismatches = %q, want one for %s", rep.Mismatches, tt.expect)
+			}
+		})
+	}
+}
+
+// follower presses each charge scheme the way the game's handler for it
+// does, at the first step each cue allows, up to taps presses.
+type follower struct {
+	taps int
+	beat int
+	last Button
+}
+
+func (p *follower) Presses(f *Flight) []Button {
+	if f.StagePending() {
+		return []Button{Stage}
+	}
+	if !f.CanCharge() || p.taps == 0 {
+		return nil
+	}
+	t := f.PrepDuration
+	switch f.Scheme {
+	case Rhythm:
+		beat := int(math.Round(t / RhythmBeat))
+		if beat == p.beat || math.Abs(t-float64(beat)*RhythmBeat) > RhythmWindow {
+			return nil
+		}
+		p.beat = beat
+	case Scanning:
+		step := int(t / ScanStep)
+		if step == p.beat {
+			return nil
+		}
+		p.beat = step
+		p.last = Right // so the lit button comes next
+		if step%2 == 1 {
+			p.last = Left
+		}
+	}
+	p.taps--
+	p.last = otherButton(p.last)
+	return []Button{p.last}
+}
+
+// lefty presses only the left button, gap steps apart.
+type lefty struct{ gap int }
+
+func (l lefty) Presses(f *Flight) []Button {
+	if f.CanCharge() && f.StepIndex%l.gap == 0 {
+		return []Button{Left}
+	}
+	return nil
+}
+
+func TestVerifyCadence(t *testing.T) {
+	config := func(s Scheme, autoTap bool) Config {
+		cfg := Preset(Normal)
+		cfg.Scheme = s
+		cfg.Assists.AutoTap = autoTap
+		return cfg
+	}
+	human := []int{5, 7, 4, 6, 8, 5}
+	tests := []struct {
+		name   string
+		cfg    Config
+		in     Input
+		expect string // a substring of a flag wanted, or "" for none
+	}{
+		{"rhythm on the beat", config(Rhythm, false), &follower{taps: 20, beat: -1}, ""},
+		{"rhythm mashed", config(Rhythm, false), &masher{gaps: []int{3}}, "off the beat"},
+		{"scanning the lit button", config(Scanning, false), &follower{taps: 20, beat: -1}, ""},
+		{"scanning mashed", config(Scanning, false), &masher{gaps: []int{3}}, "scanning step"},
+		{"hold bursts", config(HoldRelease, false), &masher{gaps: human}, ""},
+		{"hold one button", config(HoldRelease, false), lefty{gap: 6}, "out of turn"},
+		{"auto-tap held", config(Alternate, true), &masher{gaps: []int{Rate / AutoTapRate}}, ""},
+		{"auto-tap with mashing", config(Alternate, true), &masher{gaps: append([]int{Rate / AutoTapRate}, human...)}, ""},
+		{"auto-tap faked", config(Alternate, true), &masher{gaps: []int{4}}, "identical tap intervals"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReplay(1, tt.cfg)
+			res, err := Run(tt.cfg, r.Seed, tt.in, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rep, err := Verify(r, res, DefaultLimits())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rep.Mismatches) > 0 {
+				t.Fatalf("mismatches = %q", rep.Mismatches)
+			}
+			if tt.expect == "" {
+				if len(rep.Flags) > 0 {
+					t.Errorf("flags = %q, want none", rep.Flags)
+				}
+				return
+			}
+			if !slices.ContainsFunc(rep.Flags, func(f string) bool { return strings.Contains(f, tt.expect) }) {
+				t.Errorf("flags = %q, want one containing %q", rep.Flags, tt.expect)
+			}
+		})
+	}
+}
+
+// taps lays out presses at the given steps, alternating buttons.
+func taps(steps ...int) []Tap {
+	out := make([]Tap, len(steps))
+	b := Left
+	for i, s := range steps {
+		out[i] = Tap{Step: s, Button: b}
+		b = otherButton(b)
+	}
+	return out
+}
+
+// every lays out n alternating presses gap steps apart.
+func every(n, gap int) []Tap {
+	steps := make([]int, n)
+	for i := range steps {
+		steps[i] = i * gap
+	}
+	return taps(steps...)
+}
+
+// jittered lays out n alternating presses with human-looking gaps.
+func jittered(n int) []Tap {
+	gaps := []int{5, 7, 4, 6, 8, 5, 6}
+	steps := make([]int, n)
+	for i := 1; i < n; i++ {
+		steps[i] = steps[i-1] + gaps[i%len(gaps)]
+	}
+	return taps(steps...)
+}
+
+func TestCheckTiming(t *testing.T) {
+	tests := []struct {
+		name   string
+		taps   []Tap
+		expect string // a substring of a flag wanted, or "" for none
+	}{
+		{"human", jittered(60), ""},
+		{"too few taps to judge", every(10, 6), ""},
+		{"same button twice on a step", []Tap{{Step: 3, Button: Left}, {Step: 3, Button: Left}}, "pressed twice"},
+		{"three presses on a step", []Tap{{3, Left}, {3, Right}, {3, Stage}}, "more than 2 presses"},
+		{"sustained rate", every(100, 2), "taps/s sustained"},
+		{"metronome", every(40, 6), "tap intervals vary"},
+		{
+			"identical run",
+			append(every(20, 6), taps(120, 125, 132, 136, 142, 150, 155, 161, 165, 172, 180, 185, 191, 195, 202, 210, 215, 221, 225, 232, 240, 245, 251, 255, 262, 270)...),
+			"identical tap intervals",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := CheckTiming(tt.taps, DefaultLimits())
+			if tt.expect == "" {
+				if len(flags) > 0 {
+					t.Errorf("flags = %q, want none", flags)
+				}
+				return
+			}
+			if !slices.ContainsFunc(flags, func(f string) bool { return strings.Contains(f, tt.expect) }) {
+				t.Errorf("flags = %q, want one containing %q", flags, tt.expect)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+)
+
+// WeatherKind is the sort of sky a launch flies through.
+type WeatherKind string
+
+const (
+	// Clear is still air, the original game.
+	Clear WeatherKind = ""
+	// Headwind blows down against the climb and drags the rocket back.
+	Headwind WeatherKind = "headwind"
+	// Crosswind blows across the climb; it drags a little and mostly
+	// blows the smoke away.
+	Crosswind WeatherKind = "crosswind"
+	// Thermals are rising air that lightens the coast back down.
+	Thermals WeatherKind = "thermals"
+	// Storm is strong wind and rain that drag and pull the rocket down.
+	Storm WeatherKind = "storm"
+)
+
+// WeatherKinds lists every kind in menu order.
+var WeatherKinds = []WeatherKind{Clear, Headwind, Crosswind, Thermals, Storm}
+
+func (k WeatherKind) String() string {
+	if k == Clear {
+		return "clear"
+	}
+	return string(k)
+}
+
+// ParseWeatherKind accepts a kind name in any case; "clear" and the empty
+// string both mean Clear.
+func ParseWeatherKind(name string) (WeatherKind, error) {
+	name = strings.ToLower(name)
+	for _, k := range WeatherKinds {
+		if name == k.String() || name == string(k) {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown weather %q, want one of %v", name, WeatherKinds)
+}
+
+// Weather is the sky of one launch. It is part of the Config so replays
+// fly through the same weather, and of the result so scores say what
+// they were set in.
+type Weather struct {
+	Kind WeatherKind `json:",omitempty"`
+	// Wind is the wind speed in m/s; for Thermals, the speed the air
+	// rises at.
+	Wind float64 `json:",omitempty"`
+}
+
+// windRanges are the wind speeds each kind is rolled between, in m/s.
+var windRanges = map[WeatherKind][2]float64{
+	Headwind:  {5, 20},
+	Crosswind: {5, 25},
+	Thermals:  {2, 8},
+	Storm:     {15, 30},
+}
+
+// weatherOdds weights how often each kind is rolled.
+var weatherOdds = []struct {
+	kind   WeatherKind
+	weight int
+}{
+	{Clear, 30},
+	{Headwind, 20},
+	{Crosswind, 20},
+	{Thermals, 15},
+	{Storm, 15},
+}
+
+// RollWeather draws a launch's weather from seed, so a seeded launch
+// always gets the same sky.
+func RollWeather(seed uint64) Weather {
+	rng := weatherRNG(seed)
+	total := 0
+	for _, o := range weatherOdds {
+		total += o.weight
+	}
+	n := rng.IntN(total)
+	for _, o := range weatherOdds {
+		if n -= o.weight; n < 0 {
+			return o.kind.roll(rng)
+		}
+	}
+	return Weather{}
+}
+
+// Roll draws weather of kind k with a wind strength from seed.
+func (k WeatherKind) Roll(seed uint64) Weather {
+	return k.roll(weatherRNG(seed))
+}
+
+// CheckRolled reports weather other than what RollWeather draws from seed.
+// A launch that picked its kind is refused even when the wind matches, as
+// the kind is what makes a sky easy or hard.
+func (w Weather) CheckRolled(seed uint64) error {
+	if w == RollWeather(seed) {
+		return nil
+	}
+	return fmt.Errorf("weather %s at %g m/s was not rolled from seed %d", w.Kind, w.Wind, seed)
+}
+
+func (k WeatherKind) roll(rng *rand.Rand) Weather {
+	r, ok := windRanges[k]
+	if !ok {
+		return Weather{}
+	}
+	return Weather{Kind: k, Wind: math.Round(r[0] + float64((r[1]-r[0])*rng.Float64()))}
+}
+
+// weatherRNG keeps the weather's draws apart from anything else seeded
+// with the same launch seed.
+func weatherRNG(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, 0x7ea7e4))
+}
+
+// GravityScale multiplies the rocket's coasting deceleration and terminal
+// fall speed: above 1 in a storm, below 1 in thermals.
+func (w Weather) GravityScale() float64 {
+	switch w.Kind {
+	case Thermals:
+		return 1 - float64(w.Wind*0.02)
+	case Storm:
+		return 1 + float64(w.Wind*0.008)
+	}
+	return 1
+}
+
+// Drag is the fraction of its speed the rocket loses to the air each
+// second of flight.
+func (w Weather) Drag() float64 {
+	switch w.Kind {
+	case Headwind:
+		return w.Wind * 0.001
+	case Crosswind:
+		return w.Wind * 0.0005
+	case Storm:
+		return w.Wind * 0.001
+	}
+	return 0
+}
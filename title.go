@@ -0,0 +1,617 @@
+package main
+
+import (
+	"errors"
+	"image/color"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+
+	"gitlab.com/Goodgis/go-game/hangar"
+	"gitlab.com/Goodgis/go-game/locale"
+	"gitlab.com/Goodgis/go-game/sim"
+	"gitlab.com/Goodgis/go-game/ui"
+)
+
+// scene is what the window is showing. The zero value is a flight so a
+// headless game never needs a title screen.
+type scene int
+
+const (
+	sceneFlight scene = iota
+	sceneTitle
+)
+
+const (
+	demoFile = "assets/demo.json"
+	// attractDelay is how long the title sits untouched before the demo
+	// starts; attractLinger is how long the demo's results stay up.
+	attractDelay  = 15.0
+	attractLinger = 4.0
+)
+
+// textScales are the steps the settings menu cycles text size through.
+var textScales = []float64{0.75, 1, 1.25, 1.5, 2}
+
+// titleScreen is the menu the game opens on. Its modes page picks the
+// difficulty, scheme and assists the next launches use.
+type titleScreen struct {
+	opts options
+	demo *sim.Replay
+
+	page  *menuPage
+	main  *menuPage
+	clock float64
+	idle  float64
+	quit  bool
+	// calibrating is the latency calibration running on the page shown.
+	calibrating *calibration
+
+	cursorX, cursorY int
+}
+
+// menuPage is one screen of the menu. Its texts are looked up every frame
+// so a language switch shows at once.
+type menuPage struct {
+	root *ui.Root
+	sync []func()
+}
+
+func newTitleScreen(opts options) *titleScreen {
+	t := &titleScreen{opts: opts}
+	demo, err := sim.LoadReplay(demoFile)
+	if err != nil {
+		log.Println("no attract demo:", err)
+	} else {
+		t.demo = demo
+	}
+	return t
+}
+
+// build creates the pages, for example again after the text size changes.
+func (t *titleScreen) build(g *Game) {
+	t.main = nil
+	t.main = t.layout(g, &menuPage{}, "title.name", nil, []menuEntry{
+		{text: g.label("title.play"), do: g.play},
+		{text: g.label("title.campaign"), do: func() { t.show(t.campaignPage(g)) }},
+		{text: g.label("title.modes"), do: func() { t.show(t.modesPage(g)) }},
+		{text: g.label("title.hangar"), do: func() { t.show(t.hangarPage(g)) }},
+		{text: g.label("title.stats"), do: func() { t.show(t.statsPage(g)) }},
+		{text: g.label("title.achievements"), do: func() { t.show(t.achievementsPage(g)) }},
+		{text: g.label("title.settings"), do: func() { t.show(t.settingsPage(g)) }},
+		{text: g.label("title.quit"), do: func() { t.quit = true }},
+	})
+	t.main.root.Focus.OnBack = nil
+	t.page = t.main
+}
+
+func (t *titleScreen) show(p *menuPage) {
+	t.page = p
+	t.calibrating = nil
+}
+
+// menuEntry is a button on a page; text is looked up each frame.
+type menuEntry struct {
+	text func() string
+	do   func()
+}
+
+// text adds a line of small text to the page, looked up each frame.
+func (p *menuPage) text(line func() string) *ui.Label {
+	l := &ui.Label{Plain: true, Face: smallFont}
+	p.sync = append(p.sync, func() { l.Text = line() })
+	return l
+}
+
+// layout lays out a titled panel of body widgets above the page's buttons.
+// Pages other than the main one end with Back, which Escape also does.
+func (t *titleScreen) layout(g *Game, p *menuPage, title string, body []ui.Widget, entries []menuEntry) *menuPage {
+	heading := &ui.Label{Face: bigFont}
+	buttonFace := myFont
+	if t.main != nil {
+		heading.Face = myFont
+		buttonFace = smallFont
+		entries = append(entries, menuEntry{text: g.label("title.back"), do: func() { t.show(t.main) }})
+	}
+	p.sync = append(p.sync, func() { heading.Text = g.lang.T(title) })
+	panel := &ui.Panel{
+		Box:        ui.Box{Width: 420},
+		Padding:    20,
+		Spacing:    6,
+		Align:      ui.AlignCenter,
+		Background: color.RGBA{18, 22, 36, 210},
+		Children:   body,
+	}
+	if len(body) > 0 {
+		panel.Children = append(panel.Children, &ui.Spacer{Box: ui.Box{Height: 6}})
+	}
+	for _, e := range entries {
+		b := &ui.Button{Box: ui.Box{Width: 360}, Face: buttonFace, Padding: 4, OnActivate: e.do}
+		p.sync = append(p.sync, func() { b.Text = e.text() })
+		panel.Children = append(panel.Children, b)
+	}
+	p.root = ui.NewRoot(newTheme(), screenWidth, screenHeight, &ui.Overlay{
+		Children: []ui.Placement{
+			{Anchor: ui.TopCenter, Y: 40, Child: heading},
+			{Anchor: ui.Center, Y: 40, Child: panel},
+		},
+	})
+	p.root.Focus.OnBack = func() { t.show(t.main) }
+	return p
+}
+
+// label returns a lookup of a fixed message.
+func (g *Game) label(key string) func() string {
+	return func() string { return g.lang.T(key) }
+}
+
+func (g *Game) onOff(on bool) string {
+	if on {
+		return g.lang.T("menu.on")
+	}
+	return g.lang.T("menu.off")
+}
+
+// cycle returns the entry after cur in list, wrapping around.
+func cycle[T comparable](list []T, cur T) T {
+	for i, v := range list {
+		if v == cur {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
+
+func (t *titleScreen) modesPage(g *Game) *menuPage {
+	o := &t.opts
+	p := &menuPage{}
+	body := []ui.Widget{p.text(func() string { return g.goalText(o.goal()) })}
+	return t.layout(g, p, "title.modes", body, []menuEntry{
+		{
+			text: func() string { return g.lang.T("menu.mode", g.goalName(o.goal())) },
+			do: func() {
+				m, _ := sim.ParseMode(o.Mode)
+				o.Mode = cycle(sim.Modes, m).String()
+			},
+		},
+		{
+			text: func() string { return g.lang.T("menu.difficulty", g.lang.T("difficulty."+o.Difficulty)) },
+			do: func() {
+				d, _ := sim.ParseDifficulty(o.Difficulty)
+				o.Difficulty = string(cycle(sim.Difficulties, d))
+			},
+		},
+		{
+			text: func() string {
+				s, _ := sim.ParseScheme(o.Scheme)
+				return g.lang.T("menu.scheme", g.lang.T("scheme."+s.String()))
+			},
+			do: func() {
+				s, _ := sim.ParseScheme(o.Scheme)
+				o.Scheme = cycle(sim.Schemes, s).String()
+			},
+		},
+		{
+			text: func() string { return g.lang.T("menu.weather", g.weatherName(o.Weather)) },
+			do:   func() { o.Weather = cycle(weatherSettings, o.Weather) },
+		},
+		{
+			text: func() string { return g.lang.T("menu.auto_tap", g.onOff(o.AutoTap)) },
+			do:   func() { o.AutoTap = !o.AutoTap },
+		},
+		{
+			text: func() string { return g.lang.T("menu.wide_combo", g.onOff(o.WideCombo)) },
+			do:   func() { o.WideCombo = !o.WideCombo },
+		},
+	})
+}
+
+func (t *titleScreen) settingsPage(g *Game) *menuPage {
+	entries := []menuEntry{
+		{
+			text: func() string { return g.lang.T("menu.language", g.lang.Name) },
+			do:   g.cycleLanguage,
+		},
+		{
+			text: func() string { return g.lang.T("menu.units", g.lang.T("units."+g.units.String())) },
+			do:   func() { g.units = cycle([]locale.Units{locale.Metric, locale.Imperial}, g.units) },
+		},
+		{
+			text: func() string {
+				return g.lang.T("menu.text_size", g.lang.Number(g.access.textScale()*100, 0))
+			},
+			do: func() {
+				g.access.TextScale = cycle(textScales, g.access.textScale())
+				g.rebuildUI()
+				t.show(t.settingsPage(g))
+			},
+		},
+		{
+			text: func() string { return g.lang.T("menu.high_contrast", g.onOff(g.access.HighContrast)) },
+			do: func() {
+				g.access.HighContrast = !g.access.HighContrast
+				g.rebuildUI()
+				t.show(t.settingsPage(g))
+			},
+		},
+		{
+			text: func() string { return g.lang.T("menu.reduced_motion", g.onOff(g.access.ReducedMotion)) },
+			do:   func() { g.access.ReducedMotion = !g.access.ReducedMotion },
+		},
+		{
+			text: func() string { return g.lang.T("menu.captions", g.onOff(g.access.Captions)) },
+			do:   func() { g.access.Captions = !g.access.Captions },
+		},
+		{
+			text: func() string { return g.lang.T("menu.latency", g.lang.Number(g.latency*1000, 0)) },
+			do:   func() { t.calibrate(g) },
+		},
+	}
+	if musicPlayer != nil {
+		entries = append(entries, menuEntry{
+			text: func() string { return g.lang.T("menu.music", g.onOff(musicPlayer.IsPlaying())) },
+			do: func() {
+				if musicPlayer.IsPlaying() {
+					musicPlayer.Pause()
+				} else {
+					musicPlayer.Play()
+				}
+			},
+		})
+	}
+	return t.layout(g, &menuPage{}, "title.settings", nil, entries)
+}
+
+func (t *titleScreen) statsPage(g *Game) *menuPage {
+	p := &menuPage{}
+	entries, err := loadHistory()
+	if err != nil {
+		log.Println("failed to load run history:", err)
+	}
+	if len(entries) == 0 {
+		return t.layout(g, p, "title.stats", []ui.Widget{p.text(g.label("stats.none"))}, nil)
+	}
+	var total float64
+	taps := 0
+	failures := make(map[sim.Failure]int)
+	best := make(map[string]sim.Outcome)
+	for _, e := range entries {
+		total += e.Result.Altitude
+		taps += e.Result.TapCount
+		if e.Result.Failure != sim.NoFailure {
+			failures[e.Result.Failure]++
+		}
+		cat := e.Result.CategoryOrNormal()
+		o := e.Result.OutcomeOrClassic()
+		if b, ok := best[cat]; o.Missed || ok && !sim.GoalOf(cat).Mode.Beats(o, b) {
+			continue
+		}
+		best[cat] = o
+	}
+	body := []ui.Widget{
+		p.text(func() string { return g.lang.N("stats.runs", len(entries), g.lang.Number(float64(len(entries)), 0)) }),
+		p.text(func() string { return g.lang.T("stats.average", g.length(total/float64(len(entries)))) }),
+		p.text(func() string { return g.lang.T("stats.taps", g.lang.Number(float64(taps), 0)) }),
+	}
+	if len(failures) > 0 {
+		body = append(body, p.text(func() string {
+			n := func(k sim.Failure) string { return g.lang.Number(float64(failures[k]), 0) }
+			return g.lang.T("stats.failures", n(sim.EngineFailure), n(sim.StagingFailure), n(sim.StructuralFailure))
+		}))
+	}
+	cats := make([]string, 0, len(best))
+	for cat := range best {
+		cats = append(cats, cat)
+	}
+	sort.Strings(cats)
+	for _, cat := range cats {
+		body = append(body, p.text(func() string {
+			return g.lang.T("stats.best", g.categoryName(cat), g.formatScore(sim.GoalOf(cat).Mode, best[cat].Score))
+		}))
+	}
+	return t.layout(g, p, "title.stats", body, nil)
+}
+
+// achievementsPage lists every achievement; the one selected has its
+// description shown below the list.
+func (t *titleScreen) achievementsPage(g *Game) *menuPage {
+	p := &menuPage{}
+	entries, err := loadHistory()
+	if err != nil {
+		log.Println("failed to load run history:", err)
+	}
+	got := unlockedAchievements(entries)
+	n := 0
+	for _, ok := range got {
+		if ok {
+			n++
+		}
+	}
+	list := &ui.List{Face: smallFont, Spacing: 4, Items: make([]string, len(achievements))}
+	p.sync = append(p.sync, func() {
+		for i, a := range achievements {
+			mark := "[ ] "
+			if got[i] {
+				mark = "[x] "
+			}
+			list.Items[i] = mark + g.lang.T("ach."+a.id+".name")
+		}
+	})
+	body := []ui.Widget{
+		p.text(func() string { return g.lang.T("ach.count", n, len(achievements)) }),
+		list,
+		p.text(func() string { return g.lang.T("ach." + achievements[list.Selected].id + ".desc") }),
+	}
+	return t.layout(g, p, "title.achievements", body, nil)
+}
+
+// hangarPage shows the rocket, what it is built to do and a button per
+// slot cycling its parts. Parts the player owns are fitted as soon as
+// they are picked, if the rocket can still lift off; the last one picked
+// that they do not own is offered for sale, or for the achievement that
+// unlocks it.
+func (t *titleScreen) hangarPage(g *Game) *menuPage {
+	c := hangarParts
+	p := &menuPage{}
+	earned := earnedAchievements()
+	browse := g.fitted
+	var want *hangar.Part
+	status := func() string { return g.lang.T("hangar.rate", hangar.MetresPerCredit) }
+
+	name := func(part hangar.Part) string { return g.lang.T("part." + part.ID) }
+	fit := func(part hangar.Part) bool {
+		l := c.Set(g.profile.Loadout, part)
+		if err := c.Rocket(l).Check(); err != nil {
+			twr := c.Rocket(l).TWR()
+			status = func() string { return g.lang.T("hangar.grounded", name(part), g.lang.Number(twr, 2)) }
+			return false
+		}
+		g.profile.Loadout = l
+		g.saveProfile()
+		g.refit()
+		t.apply(g)
+		g.applyLoadout()
+		status = func() string { return g.lang.T("hangar.fitted", name(part)) }
+		return true
+	}
+
+	// The stats are for the rocket on show. They are only worked out
+	// again when it changes, as finding the best charge flies it fifty
+	// times.
+	var shown sim.Loadout
+	stale := true
+	var rocket sim.Rocket
+	var charge, ceiling float64
+	preview := &ui.Image{Box: ui.Box{Width: 80, Height: 130}}
+	p.sync = append(p.sync, func() {
+		preview.Image = rocketSprite(browse)
+		if !stale && shown == browse {
+			return
+		}
+		shown, stale = browse, false
+		rocket = c.Rocket(browse)
+		charge, ceiling = 0, 0
+		if rocket.Check() == nil {
+			charge, ceiling = rocket.Apply(t.opts.config()).BestCharge()
+		}
+	})
+	info := &ui.Panel{
+		Spacing: 2,
+		Children: append([]ui.Widget{
+			p.text(func() string { return g.lang.T("hangar.credits", g.profile.Credits) }),
+			p.text(func() string {
+				return g.lang.T("hangar.twr", g.lang.Number(rocket.TWR(), 2), g.lang.Number(rocket.Fuel, 0))
+			}),
+			p.text(func() string {
+				if rocket.Check() != nil {
+					return g.lang.T("hangar.no_liftoff")
+				}
+				return g.lang.T("hangar.best", g.lang.Number(charge*100, 0), g.length(ceiling))
+			}),
+		}, p.wrapped(2, 280, func() string { return status() })...),
+	}
+	body := []ui.Widget{&ui.Panel{
+		Direction: ui.Horizontal,
+		Spacing:   12,
+		Align:     ui.AlignCenter,
+		Children:  []ui.Widget{preview, info},
+	}}
+	var entries []menuEntry
+	for _, s := range hangar.Slots {
+		entries = append(entries, menuEntry{
+			text: func() string {
+				part := c.Get(browse, s)
+				label := name(part)
+				switch {
+				case g.profile.Owns(part, earned):
+				case part.Achievement != "":
+					label = g.lang.T("hangar.locked", label)
+				default:
+					label = g.lang.T("hangar.price", label, part.Price)
+				}
+				return g.lang.T("hangar.slot", g.lang.T("slot."+string(s)), label)
+			},
+			do: func() {
+				part := cycle(c.InSlot(s), c.Get(browse, s))
+				browse = c.Set(browse, part)
+				if !g.profile.Owns(part, earned) {
+					want = &part
+					return
+				}
+				if want != nil && want.Slot == s {
+					want = nil
+				}
+				fit(part)
+			},
+		})
+	}
+	entries = append(entries, menuEntry{
+		text: func() string {
+			switch {
+			case want == nil:
+				return g.lang.T("hangar.nothing")
+			case want.Achievement != "":
+				return g.lang.T("hangar.unlock", g.lang.T("ach."+want.Achievement+".name"), name(*want))
+			}
+			return g.lang.T("hangar.buy", name(*want), want.Price)
+		},
+		do: func() {
+			if want == nil || want.Achievement != "" {
+				return
+			}
+			part := *want
+			switch err := g.profile.Buy(part); {
+			case errors.Is(err, hangar.ErrCredits):
+				short := part.Price - g.profile.Credits
+				status = func() string { return g.lang.T("hangar.poor", short) }
+			case err != nil:
+				log.Println("failed to buy part:", err)
+			default:
+				want = nil
+				g.saveProfile()
+				if fit(part) {
+					status = func() string { return g.lang.T("hangar.bought", name(part)) }
+				}
+			}
+		},
+	})
+	return t.layout(g, p, "title.hangar", body, entries)
+}
+
+// update runs the menu for one tick and starts the demo once the player
+// has left it alone for attractDelay.
+func (t *titleScreen) update(g *Game) error {
+	dt := g.clock.FrameDelta()
+	t.clock += dt
+	t.idle += dt
+	if t.anyInput() {
+		t.idle = 0
+	}
+	if c := t.calibrating; c != nil && !c.done {
+		c.update(g)
+		if g.JustPressed(ebiten.KeyEscape) {
+			t.show(t.main)
+		}
+		g.animate(dt)
+		return nil
+	}
+	t.page.root.Update()
+	if t.quit {
+		return ebiten.Termination
+	}
+	if t.idle >= attractDelay && t.demo != nil {
+		g.startAttract()
+		return nil
+	}
+	g.animate(dt)
+	return nil
+}
+
+// anyInput reports a key, click, cursor move or gamepad button this tick.
+func (t *titleScreen) anyInput() bool {
+	x, y := ebiten.CursorPosition()
+	moved := x != t.cursorX || y != t.cursorY
+	t.cursorX, t.cursorY = x, y
+	if moved || len(inpututil.AppendJustPressedKeys(nil)) > 0 ||
+		inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if len(inpututil.AppendJustPressedStandardGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *titleScreen) draw(dst *ebiten.Image, g *Game) {
+	// The rocket idles on the pad, rumbling while it vents steam.
+	rumble := 0.0
+	if !g.access.ReducedMotion {
+		rumble = math.Sin(t.clock*40) * 0.8
+	}
+	g.drawWorld(dst, 0, 0, rumble)
+	for _, s := range t.page.sync {
+		s()
+	}
+	t.page.root.Draw(dst, 0, 0)
+	if c := t.calibrating; c != nil && !c.done {
+		c.draw(dst)
+	}
+}
+
+// apply makes the menu's chosen difficulty, scheme and assists the ones
+// the next launch uses.
+func (t *titleScreen) apply(g *Game) {
+	cfg := g.build(t.opts.config())
+	g.flight.Config = cfg
+	g.scheme = newChargeScheme(cfg.Scheme)
+	g.weather = t.opts.Weather
+	g.saved_highscore = g.highscores[cfg.Category()]
+}
+
+// play leaves the menu for a launch.
+func (g *Game) play() {
+	g.title.apply(g)
+	g.scene = sceneFlight
+	g.restartGame()
+}
+
+// showTitle abandons the current launch or demo for the main menu.
+func (g *Game) showTitle() {
+	if g.attract {
+		g.attract = false
+		g.playback = nil
+	}
+	g.title.apply(g)
+	g.title.idle = 0
+	g.title.show(g.title.main)
+	if g.mission != nil {
+		g.mission = nil
+		g.title.show(g.title.campaignPage(g))
+	}
+	g.scene = sceneTitle
+	g.restartGame()
+}
+
+// startAttract plays the bundled demo replay until any input.
+func (g *Game) startAttract() {
+	demo := g.title.demo
+	g.attract = true
+	g.attractLeft = attractLinger
+	g.playback = sim.NewPlayback(demo)
+	g.flight.Config = demo.Config
+	g.scheme = newChargeScheme(demo.Config.Scheme)
+	g.scene = sceneFlight
+	g.restartGame()
+}
+
+// updateAttract returns to the title on any input or once the demo's
+// results have been shown for attractLinger.
+func (g *Game) updateAttract() bool {
+	if g.title.anyInput() {
+		g.showTitle()
+		return true
+	}
+	if g.flight.Over {
+		g.attractLeft -= g.clock.FrameDelta()
+		if g.attractLeft <= 0 {
+			g.showTitle()
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildUI rebuilds the fonts and widget trees after a change to the text
+// size or palette.
+func (g *Game) rebuildUI() {
+	loadFaces(g.access.textScale())
+	g.hud = newHUD(g.access)
+	if g.title != nil {
+		g.title.build(g)
+	}
+}
@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Nav is a single navigation request from the keyboard or a gamepad.
+type Nav int
+
+const (
+	NavNone Nav = iota
+	NavUp
+	NavDown
+	NavLeft
+	NavRight
+	NavActivate
+	NavBack
+)
+
+// Focusable widgets can hold keyboard/gamepad focus.
+type Focusable interface {
+	Widget
+	SetFocused(bool)
+	// Navigate gives the widget first refusal on a direction press and
+	// reports whether it consumed it.
+	Navigate(Nav) bool
+	Activate()
+}
+
+// Pointable widgets respond to where inside them the mouse is, as a list
+// selecting the row under the cursor.
+type Pointable interface {
+	Point(x, y float64)
+}
+
+// Focus is the ring of focusable widgets in a tree, in declaration order.
+type Focus struct {
+	items   []Focusable
+	current int
+	OnBack  func()
+
+	cursorX, cursorY int
+}
+
+// NewFocus collects every focusable widget under w and focuses the first.
+func NewFocus(w Widget) *Focus {
+	f := &Focus{}
+	f.collect(w)
+	f.set(0)
+	return f
+}
+
+func (f *Focus) collect(w Widget) {
+	if w == nil {
+		return
+	}
+	if fw, ok := w.(Focusable); ok {
+		f.items = append(f.items, fw)
+	}
+	if c, ok := w.(container); ok {
+		for _, child := range c.children() {
+			f.collect(child)
+		}
+	}
+}
+
+func (f *Focus) set(i int) {
+	if len(f.items) == 0 {
+		return
+	}
+	f.items[f.current].SetFocused(false)
+	f.current = i
+	f.items[f.current].SetFocused(true)
+}
+
+// Current returns the focused widget, or nil when nothing can take focus.
+func (f *Focus) Current() Focusable {
+	if len(f.items) == 0 {
+		return nil
+	}
+	return f.items[f.current]
+}
+
+// SetCurrent moves focus to w if it is part of the ring.
+func (f *Focus) SetCurrent(w Focusable) {
+	for i, it := range f.items {
+		if it == w {
+			f.set(i)
+			return
+		}
+	}
+}
+
+// step moves focus by dir, skipping hidden widgets.
+func (f *Focus) step(dir int) {
+	n := len(f.items)
+	for i := 1; i <= n; i++ {
+		next := ((f.current+dir*i)%n + n) % n
+		if f.items[next].Visible() {
+			f.set(next)
+			return
+		}
+	}
+}
+
+// Apply handles one navigation request.
+func (f *Focus) Apply(n Nav) {
+	if n == NavBack {
+		if f.OnBack != nil {
+			f.OnBack()
+		}
+		return
+	}
+	cur := f.Current()
+	if cur == nil {
+		return
+	}
+	if cur.Navigate(n) {
+		return
+	}
+	switch n {
+	case NavUp, NavLeft:
+		f.step(-1)
+	case NavDown, NavRight:
+		f.step(1)
+	case NavActivate:
+		cur.Activate()
+	}
+}
+
+// Update polls the keyboard and every connected gamepad for navigation,
+// then the mouse: moving over a widget focuses it and clicking activates it.
+func (f *Focus) Update() {
+	for _, n := range PollNav() {
+		f.Apply(n)
+	}
+	f.pointer()
+}
+
+func (f *Focus) pointer() {
+	x, y := ebiten.CursorPosition()
+	moved := x != f.cursorX || y != f.cursorY
+	f.cursorX, f.cursorY = x, y
+	clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+	if !moved && !clicked {
+		return
+	}
+	for i, it := range f.items {
+		if !it.Visible() || !it.Bounds().Contains(float64(x), float64(y)) {
+			continue
+		}
+		if i != f.current {
+			f.set(i)
+		}
+		if p, ok := it.(Pointable); ok {
+			p.Point(float64(x), float64(y))
+		}
+		if clicked {
+			it.Activate()
+		}
+		return
+	}
+}
+
+var navKeys = map[ebiten.Key]Nav{
+	ebiten.KeyArrowUp:    NavUp,
+	ebiten.KeyArrowDown:  NavDown,
+	ebiten.KeyArrowLeft:  NavLeft,
+	ebiten.KeyArrowRight: NavRight,
+	ebiten.KeyEnter:      NavActivate,
+	ebiten.KeySpace:      NavActivate,
+	ebiten.KeyEscape:     NavBack,
+	ebiten.KeyBackspace:  NavBack,
+}
+
+var navButtons = map[ebiten.StandardGamepadButton]Nav{
+	ebiten.StandardGamepadButtonLeftTop:     NavUp,
+	ebiten.StandardGamepadButtonLeftBottom:  NavDown,
+	ebiten.StandardGamepadButtonLeftLeft:    NavLeft,
+	ebiten.StandardGamepadButtonLeftRight:   NavRight,
+	ebiten.StandardGamepadButtonRightBottom: NavActivate,
+	ebiten.StandardGamepadButtonRightRight:  NavBack,
+}
+
+// PollNav returns the navigation requests made since the last tick.
+func PollNav() []Nav {
+	var navs []Nav
+	for k, n := range navKeys {
+		if inpututil.IsKeyJustPressed(k) {
+			navs = append(navs, n)
+		}
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if !ebiten.IsStandardGamepadLayoutAvailable(id) {
+			continue
+		}
+		for b, n := range navButtons {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, b) {
+				navs = append(navs, n)
+			}
+		}
+	}
+	return navs
+}
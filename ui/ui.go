@@ -0,0 +1,165 @@
+// Package ui is a small retained-mode widget toolkit for the HUD, results
+// panel, menus and settings screens. Widgets are described declaratively as
+// nested struct literals, laid out once per frame from their measured sizes,
+// and kept alive between frames so callers only touch the fields that change.
+package ui
+
+import (
+	"image/color"
+
+	"golang.org/x/image/font"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text"
+)
+
+// Rect is an axis-aligned rectangle in screen pixels.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Inset shrinks the rectangle by n pixels on every side.
+func (r Rect) Inset(n float64) Rect {
+	return Rect{X: r.X + n, Y: r.Y + n, W: r.W - 2*n, H: r.H - 2*n}
+}
+
+// Contains reports whether the point (x, y) lies inside the rectangle.
+func (r Rect) Contains(x, y float64) bool {
+	return x >= r.X && x < r.X+r.W && y >= r.Y && y < r.Y+r.H
+}
+
+// Align positions a child along an axis inside the space it was given.
+type Align int
+
+const (
+	AlignStart Align = iota
+	AlignCenter
+	AlignEnd
+	AlignStretch
+)
+
+// Anchor places a child of an Overlay relative to the overlay's bounds.
+type Anchor int
+
+const (
+	TopLeft Anchor = iota
+	TopCenter
+	TopRight
+	CenterLeft
+	Center
+	CenterRight
+	BottomLeft
+	BottomCenter
+	BottomRight
+)
+
+// TextDrawer renders a single line of text with its baseline at (x, y).
+// When outline is non-nil the text is drawn with an outline of that color.
+type TextDrawer func(dst *ebiten.Image, str string, face font.Face, x, y int, clr, outline color.Color)
+
+// Theme holds the shared look of every widget in a tree.
+type Theme struct {
+	Face         font.Face
+	TextColor    color.Color
+	OutlineColor color.Color
+	FocusColor   color.Color
+	DrawText     TextDrawer
+}
+
+// DefaultTheme returns a theme that draws white outlined text in face.
+func DefaultTheme(face font.Face) *Theme {
+	return &Theme{
+		Face:         face,
+		TextColor:    color.White,
+		OutlineColor: color.Black,
+		FocusColor:   color.RGBA{255, 196, 0, 255},
+		DrawText: func(dst *ebiten.Image, str string, face font.Face, x, y int, clr, _ color.Color) {
+			text.Draw(dst, str, face, x, y, clr)
+		},
+	}
+}
+
+func (t *Theme) face(override font.Face) font.Face {
+	if override != nil {
+		return override
+	}
+	return t.Face
+}
+
+// measureText returns the advance width and line height of str in face.
+func measureText(face font.Face, str string) (w, h float64) {
+	b := text.BoundString(face, str)
+	m := face.Metrics()
+	return float64(b.Dx()), float64((m.Ascent + m.Descent).Ceil())
+}
+
+// Widget is implemented by everything that can appear in a tree.
+type Widget interface {
+	// Measure reports the size the widget would like to occupy.
+	Measure(t *Theme) (w, h float64)
+	// Arrange assigns the widget its final bounds.
+	Arrange(t *Theme, r Rect)
+	// Bounds returns the rectangle given by the last Arrange.
+	Bounds() Rect
+	// Draw renders the widget shifted by (dx, dy).
+	Draw(dst *ebiten.Image, t *Theme, dx, dy float64)
+	// Visible reports whether the widget takes part in layout and drawing.
+	Visible() bool
+}
+
+// Box carries the fields shared by every widget. Width and Height force a
+// size on the respective axis when non-zero.
+type Box struct {
+	Hidden bool
+	Width  float64
+	Height float64
+
+	bounds Rect
+}
+
+func (b *Box) Bounds() Rect  { return b.bounds }
+func (b *Box) Visible() bool { return !b.Hidden }
+
+func (b *Box) size(w, h float64) (float64, float64) {
+	if b.Width > 0 {
+		w = b.Width
+	}
+	if b.Height > 0 {
+		h = b.Height
+	}
+	return w, h
+}
+
+// Root is the top of a widget tree. It lays the tree out against a fixed
+// screen size and owns the focus ring used for keyboard and gamepad menus.
+type Root struct {
+	Theme *Theme
+	Child Widget
+	Focus *Focus
+
+	width, height float64
+}
+
+// NewRoot builds a tree for a screen of the given size and collects every
+// focusable widget inside it.
+func NewRoot(theme *Theme, width, height int, child Widget) *Root {
+	r := &Root{Theme: theme, Child: child, width: float64(width), height: float64(height)}
+	r.Focus = NewFocus(child)
+	return r
+}
+
+// Update advances focus navigation and activates the focused widget.
+func (r *Root) Update() {
+	if r.Focus != nil {
+		r.Focus.Update()
+	}
+}
+
+// Draw lays the tree out and renders it, shifted by (dx, dy).
+func (r *Root) Draw(dst *ebiten.Image, dx, dy float64) {
+	if r.Child == nil || !r.Child.Visible() {
+		return
+	}
+	r.Child.Arrange(r.Theme, Rect{W: r.width, H: r.height})
+	r.Child.Draw(dst, r.Theme, dx, dy)
+}
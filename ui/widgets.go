@@ -0,0 +1,497 @@
+package ui
+
+import (
+	"image/color"
+	"math"
+
+	"golang.org/x/image/font"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// container is implemented by widgets that hold other widgets, so the
+// focus ring can walk the whole tree.
+type container interface {
+	children() []Widget
+}
+
+func fillRect(dst *ebiten.Image, r Rect, dx, dy float64, clr color.Color) {
+	if clr == nil || r.W <= 0 || r.H <= 0 {
+		return
+	}
+	ebitenutil.DrawRect(dst, r.X+dx, r.Y+dy, r.W, r.H, clr)
+}
+
+// Direction is the main axis of a Panel.
+type Direction int
+
+const (
+	Vertical Direction = iota
+	Horizontal
+)
+
+// Panel stacks its children along one axis, optionally over a filled
+// background with a border.
+type Panel struct {
+	Box
+	Direction   Direction
+	Padding     float64
+	Spacing     float64
+	Align       Align // cross-axis placement of children
+	Justify     Align // main-axis placement when the panel is larger than its content
+	Background  color.Color
+	Border      color.Color
+	BorderWidth float64
+	Children    []Widget
+}
+
+func (p *Panel) children() []Widget { return p.Children }
+
+func (p *Panel) inset() float64 {
+	if p.Border != nil {
+		return p.Padding + p.BorderWidth
+	}
+	return p.Padding
+}
+
+func (p *Panel) Measure(t *Theme) (float64, float64) {
+	var main, cross float64
+	n := 0
+	for _, c := range p.Children {
+		if !c.Visible() {
+			continue
+		}
+		w, h := c.Measure(t)
+		if p.Direction == Horizontal {
+			w, h = h, w
+		}
+		main += h
+		cross = math.Max(cross, w)
+		n++
+	}
+	if n > 1 {
+		main += p.Spacing * float64(n-1)
+	}
+	in := 2 * p.inset()
+	if p.Direction == Horizontal {
+		return p.size(main+in, cross+in)
+	}
+	return p.size(cross+in, main+in)
+}
+
+func (p *Panel) Arrange(t *Theme, r Rect) {
+	p.bounds = r
+	inner := r.Inset(p.inset())
+
+	sizes := make([][2]float64, len(p.Children))
+	var used float64
+	n := 0
+	for i, c := range p.Children {
+		if !c.Visible() {
+			continue
+		}
+		w, h := c.Measure(t)
+		sizes[i] = [2]float64{w, h}
+		if p.Direction == Horizontal {
+			used += w
+		} else {
+			used += h
+		}
+		n++
+	}
+	if n > 1 {
+		used += p.Spacing * float64(n-1)
+	}
+
+	mainLen, crossLen := inner.H, inner.W
+	if p.Direction == Horizontal {
+		mainLen, crossLen = inner.W, inner.H
+	}
+	pos := 0.0
+	switch p.Justify {
+	case AlignCenter:
+		pos = (mainLen - used) / 2
+	case AlignEnd:
+		pos = mainLen - used
+	}
+
+	for i, c := range p.Children {
+		if !c.Visible() {
+			continue
+		}
+		m, x := sizes[i][1], sizes[i][0]
+		if p.Direction == Horizontal {
+			m, x = sizes[i][0], sizes[i][1]
+		}
+		off := 0.0
+		switch p.Align {
+		case AlignCenter:
+			off = (crossLen - x) / 2
+		case AlignEnd:
+			off = crossLen - x
+		case AlignStretch:
+			x = crossLen
+		}
+		if p.Direction == Horizontal {
+			c.Arrange(t, Rect{X: inner.X + pos, Y: inner.Y + off, W: m, H: x})
+		} else {
+			c.Arrange(t, Rect{X: inner.X + off, Y: inner.Y + pos, W: x, H: m})
+		}
+		pos += m + p.Spacing
+	}
+}
+
+func (p *Panel) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	if p.Border != nil && p.BorderWidth > 0 {
+		fillRect(dst, p.bounds, dx, dy, p.Border)
+		fillRect(dst, p.bounds.Inset(p.BorderWidth), dx, dy, p.Background)
+	} else {
+		fillRect(dst, p.bounds, dx, dy, p.Background)
+	}
+	for _, c := range p.Children {
+		if c.Visible() {
+			c.Draw(dst, t, dx, dy)
+		}
+	}
+}
+
+// Placement positions one child of an Overlay.
+type Placement struct {
+	Anchor Anchor
+	X, Y   float64
+	Child  Widget
+}
+
+// Overlay layers children on top of each other, each pinned to an anchor
+// of the overlay's bounds and nudged by an offset.
+type Overlay struct {
+	Box
+	Background color.Color
+	Children   []Placement
+}
+
+func (o *Overlay) children() []Widget {
+	ws := make([]Widget, len(o.Children))
+	for i, p := range o.Children {
+		ws[i] = p.Child
+	}
+	return ws
+}
+
+func (o *Overlay) Measure(t *Theme) (float64, float64) {
+	var w, h float64
+	for _, p := range o.Children {
+		if !p.Child.Visible() {
+			continue
+		}
+		cw, ch := p.Child.Measure(t)
+		w = math.Max(w, cw+math.Abs(p.X))
+		h = math.Max(h, ch+math.Abs(p.Y))
+	}
+	return o.size(w, h)
+}
+
+func (o *Overlay) Arrange(t *Theme, r Rect) {
+	if o.Width > 0 {
+		r.W = o.Width
+	}
+	if o.Height > 0 {
+		r.H = o.Height
+	}
+	o.bounds = r
+	for _, p := range o.Children {
+		if !p.Child.Visible() {
+			continue
+		}
+		w, h := p.Child.Measure(t)
+		x, y := r.X, r.Y
+		switch p.Anchor {
+		case TopCenter, Center, BottomCenter:
+			x += (r.W - w) / 2
+		case TopRight, CenterRight, BottomRight:
+			x += r.W - w
+		}
+		switch p.Anchor {
+		case CenterLeft, Center, CenterRight:
+			y += (r.H - h) / 2
+		case BottomLeft, BottomCenter, BottomRight:
+			y += r.H - h
+		}
+		p.Child.Arrange(t, Rect{X: x + p.X, Y: y + p.Y, W: w, H: h})
+	}
+}
+
+func (o *Overlay) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	fillRect(dst, o.bounds, dx, dy, o.Background)
+	for _, p := range o.Children {
+		if p.Child.Visible() {
+			p.Child.Draw(dst, t, dx, dy)
+		}
+	}
+}
+
+// Spacer is an empty widget that reserves space in a Panel.
+type Spacer struct {
+	Box
+}
+
+func (s *Spacer) Measure(*Theme) (float64, float64)            { return s.size(0, 0) }
+func (s *Spacer) Arrange(_ *Theme, r Rect)                     { s.bounds = r }
+func (s *Spacer) Draw(*ebiten.Image, *Theme, float64, float64) {}
+
+// Image draws a picture scaled to fit its bounds, keeping its aspect
+// ratio, centred.
+type Image struct {
+	Box
+	Image *ebiten.Image
+}
+
+func (i *Image) Measure(*Theme) (float64, float64) {
+	if i.Image == nil {
+		return i.size(0, 0)
+	}
+	b := i.Image.Bounds()
+	return i.size(float64(b.Dx()), float64(b.Dy()))
+}
+
+func (i *Image) Arrange(_ *Theme, r Rect) { i.bounds = r }
+
+func (i *Image) Draw(dst *ebiten.Image, _ *Theme, dx, dy float64) {
+	if i.Image == nil {
+		return
+	}
+	b := i.Image.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	scale := math.Min(i.bounds.W/w, i.bounds.H/h)
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(i.bounds.X+(i.bounds.W-w*scale)/2+dx, i.bounds.Y+(i.bounds.H-h*scale)/2+dy)
+	dst.DrawImage(i.Image, op)
+}
+
+// Label draws one line of text. Plain labels skip the outline pass.
+type Label struct {
+	Box
+	Text  string
+	Face  font.Face
+	Color color.Color
+	Plain bool
+	Align Align
+}
+
+func (l *Label) Measure(t *Theme) (float64, float64) {
+	w, h := measureText(t.face(l.Face), l.Text)
+	return l.size(w, h)
+}
+
+func (l *Label) Arrange(_ *Theme, r Rect) { l.bounds = r }
+
+func (l *Label) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	drawTextIn(dst, t, l.Face, l.Text, l.Color, !l.Plain, l.Align, l.bounds, dx, dy)
+}
+
+// drawTextIn draws str vertically centred in r with the given horizontal
+// alignment.
+func drawTextIn(dst *ebiten.Image, t *Theme, face font.Face, str string, clr color.Color, outline bool, align Align, r Rect, dx, dy float64) {
+	if str == "" {
+		return
+	}
+	face = t.face(face)
+	if clr == nil {
+		clr = t.TextColor
+	}
+	w, _ := measureText(face, str)
+	m := face.Metrics()
+	x := r.X
+	switch align {
+	case AlignCenter, AlignStretch:
+		x += (r.W - w) / 2
+	case AlignEnd:
+		x += r.W - w
+	}
+	baseline := r.Y + (r.H+float64(m.Ascent.Ceil())-float64(m.Descent.Ceil()))/2
+	var oc color.Color
+	if outline {
+		oc = t.OutlineColor
+	}
+	t.DrawText(dst, str, face, int(math.Round(x+dx)), int(math.Round(baseline+dy)), clr, oc)
+}
+
+// ProgressBar shows Value, clamped to [0, 1], as a filled track with an
+// optional centred caption.
+type ProgressBar struct {
+	Box
+	Value float64
+	Inset float64
+	Track color.Color
+	Fill  color.Color
+	Label string
+	Face  font.Face
+	// Border, when set, rings the track BorderWidth thick.
+	Border      color.Color
+	BorderWidth float64
+	// Mark, when between 0 and 1, tints the track beyond that fraction
+	// in MarkColor, as a red zone.
+	Mark      float64
+	MarkColor color.Color
+}
+
+func (b *ProgressBar) Measure(*Theme) (float64, float64) { return b.size(200, 20) }
+func (b *ProgressBar) Arrange(_ *Theme, r Rect)          { b.bounds = r }
+
+func (b *ProgressBar) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	track := b.bounds
+	if b.Border != nil && b.BorderWidth > 0 {
+		fillRect(dst, track, dx, dy, b.Border)
+		track = track.Inset(b.BorderWidth)
+	}
+	fillRect(dst, track, dx, dy, b.Track)
+	inner := track.Inset(b.Inset)
+	if b.Mark > 0 && b.Mark < 1 && b.MarkColor != nil {
+		zone := inner
+		zone.X += zone.W * b.Mark
+		zone.W *= 1 - b.Mark
+		fillRect(dst, zone, dx, dy, b.MarkColor)
+	}
+	v := math.Max(0, math.Min(1, b.Value))
+	inner.W *= v
+	fillRect(dst, inner, dx, dy, b.Fill)
+	drawTextIn(dst, t, b.Face, b.Label, nil, true, AlignCenter, b.bounds, dx, dy)
+}
+
+// Button is a focusable label that calls OnActivate when pressed.
+type Button struct {
+	Box
+	Text       string
+	Face       font.Face
+	Padding    float64
+	Background color.Color
+	OnActivate func()
+
+	focused bool
+}
+
+func (b *Button) Measure(t *Theme) (float64, float64) {
+	w, h := measureText(t.face(b.Face), b.Text)
+	return b.size(w+2*b.Padding, h+2*b.Padding)
+}
+
+func (b *Button) Arrange(_ *Theme, r Rect) { b.bounds = r }
+
+func (b *Button) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	fillRect(dst, b.bounds, dx, dy, b.Background)
+	clr := t.TextColor
+	if b.focused {
+		clr = t.FocusColor
+	}
+	drawTextIn(dst, t, b.Face, b.Text, clr, true, AlignCenter, b.bounds, dx, dy)
+}
+
+func (b *Button) SetFocused(f bool) { b.focused = f }
+func (b *Button) Navigate(Nav) bool { return false }
+func (b *Button) Activate() {
+	if b.OnActivate != nil {
+		b.OnActivate()
+	}
+}
+
+// List is a focusable column of selectable rows. Up and down move the
+// selection while it has somewhere to go and hand focus on at either end.
+type List struct {
+	Box
+	Items      []string
+	Selected   int
+	Rows       int // rows shown at once; 0 shows every item
+	Face       font.Face
+	Spacing    float64
+	OnSelect   func(i int)
+	OnActivate func(i int)
+
+	focused bool
+	scroll  int
+}
+
+func (l *List) rows() int {
+	if l.Rows <= 0 || l.Rows > len(l.Items) {
+		return len(l.Items)
+	}
+	return l.Rows
+}
+
+func (l *List) Measure(t *Theme) (float64, float64) {
+	face := t.face(l.Face)
+	var w float64
+	for _, it := range l.Items {
+		iw, _ := measureText(face, it)
+		w = math.Max(w, iw)
+	}
+	_, h := measureText(face, "M")
+	n := float64(l.rows())
+	return l.size(w, n*h+math.Max(0, n-1)*l.Spacing)
+}
+
+func (l *List) Arrange(_ *Theme, r Rect) { l.bounds = r }
+
+func (l *List) Draw(dst *ebiten.Image, t *Theme, dx, dy float64) {
+	face := t.face(l.Face)
+	_, h := measureText(face, "M")
+	y := l.bounds.Y
+	for i := l.scroll; i < l.scroll+l.rows() && i < len(l.Items); i++ {
+		clr := t.TextColor
+		if i == l.Selected && l.focused {
+			clr = t.FocusColor
+		}
+		drawTextIn(dst, t, l.Face, l.Items[i], clr, true, AlignStart, Rect{X: l.bounds.X, Y: y, W: l.bounds.W, H: h}, dx, dy)
+		y += h + l.Spacing
+	}
+}
+
+func (l *List) SetFocused(f bool) { l.focused = f }
+
+func (l *List) Navigate(n Nav) bool {
+	next := l.Selected
+	switch n {
+	case NavUp:
+		next--
+	case NavDown:
+		next++
+	default:
+		return false
+	}
+	if next < 0 || next >= len(l.Items) {
+		return false
+	}
+	l.Selected = next
+	if l.Selected < l.scroll {
+		l.scroll = l.Selected
+	} else if l.Selected >= l.scroll+l.rows() {
+		l.scroll = l.Selected - l.rows() + 1
+	}
+	if l.OnSelect != nil {
+		l.OnSelect(l.Selected)
+	}
+	return true
+}
+
+// Point selects the row under the cursor.
+func (l *List) Point(_, y float64) {
+	if len(l.Items) == 0 || l.bounds.H <= 0 {
+		return
+	}
+	n := l.rows()
+	row := l.scroll + int((y-l.bounds.Y)/l.bounds.H*float64(n))
+	if row < l.scroll || row >= l.scroll+n || row == l.Selected {
+		return
+	}
+	l.Selected = row
+	if l.OnSelect != nil {
+		l.OnSelect(row)
+	}
+}
+
+func (l *List) Activate() {
+	if l.OnActivate != nil && l.Selected >= 0 && l.Selected < len(l.Items) {
+		l.OnActivate(l.Selected)
+	}
+}
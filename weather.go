@@ -0,0 +1,139 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// randomWeather is the weather setting that rolls a new sky each launch;
+// the others name a sim.WeatherKind to always fly in.
+const randomWeather = "random"
+
+// weatherSettings lists the settings in menu order.
+var weatherSettings = func() []string {
+	list := []string{randomWeather}
+	for _, k := range sim.WeatherKinds {
+		list = append(list, k.String())
+	}
+	return list
+}()
+
+// rollWeather draws the sky for a launch from its seed.
+func rollWeather(setting string, seed uint64) sim.Weather {
+	if setting == randomWeather {
+		return sim.RollWeather(seed)
+	}
+	k, _ := sim.ParseWeatherKind(setting)
+	return k.Roll(seed)
+}
+
+// forecast describes the weather in the player's language.
+func (g *Game) forecast(w sim.Weather) string {
+	if w.Kind == sim.Clear {
+		return g.lang.T("forecast.clear")
+	}
+	return g.lang.T("forecast."+w.Kind.String(), g.lang.Speed(w.Wind, g.units))
+}
+
+// weatherName is a weather setting's name for the menu.
+func (g *Game) weatherName(setting string) string {
+	return g.lang.T("weather." + setting)
+}
+
+// weatherDrift is how many times faster than in still air the clouds and
+// high-altitude scenery move.
+func weatherDrift(w sim.Weather) float64 {
+	switch w.Kind {
+	case sim.Crosswind, sim.Storm:
+		return 1 + w.Wind/8
+	case sim.Headwind:
+		return 1 + w.Wind/20
+	}
+	return 1
+}
+
+// particleWind is the sideways push the wind gives smoke and sparks, in
+// pixels a second squared; it blows the same way as the clouds.
+func particleWind(w sim.Weather) float64 {
+	switch w.Kind {
+	case sim.Crosswind, sim.Storm:
+		return -w.Wind * 6
+	}
+	return 0
+}
+
+// cloudTint darkens the clouds under a storm.
+func (g *Game) cloudTint() color.RGBA {
+	if g.flight.Weather.Kind == sim.Storm {
+		return color.RGBA{120, 125, 140, 255}
+	}
+	return color.RGBA{255, 255, 255, 255}
+}
+
+// drawWeather adds the weather to the troposphere: rain and lightning in
+// a storm, gusts in a wind and shimmer over thermals. It fades out as the
+// rocket climbs into the jet stream.
+func drawWeather(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	w := g.flight.Weather
+	fade := 1 - (cam.Y-rocketBaseY)/sim.Zones[1].Floor
+	if w.Kind == sim.Clear || fade <= 0 {
+		return
+	}
+	fade = math.Min(1, fade)
+	width, height := float64(screenWidth), float64(screenHeight)
+	streak := func(x, y, w, h float64, clr color.RGBA) {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(w, h)
+		op.GeoM.Translate(x+shakeX, y+shakeY)
+		op.ColorScale.ScaleWithColor(clr)
+		op.ColorScale.ScaleAlpha(float32(fade))
+		dst.DrawImage(whitePixel, op)
+	}
+	t := g.skyTime
+	switch w.Kind {
+	case sim.Storm:
+		for i := uint64(0); i < 120; i++ {
+			h := hash64(i + 3<<32)
+			x := math.Mod(unit(h)*width*2-t*w.Wind*12, width*2)
+			if x < 0 {
+				x += width * 2
+			}
+			y := math.Mod(unit(hash64(h))*height+t*(700+unit(hash64(h+1))*200), height)
+			streak(x-width/2, y, 1.5, 14, color.RGBA{170, 190, 220, 110})
+		}
+		// A flash now and then, but never for players who asked for
+		// less motion.
+		if beat := math.Floor(t * 2); !g.access.ReducedMotion && hash64(uint64(beat))%17 == 0 && t*2-beat < 0.08 {
+			streak(0, 0, width, height, color.RGBA{255, 255, 255, 90})
+		}
+	case sim.Crosswind, sim.Headwind:
+		for i := uint64(0); i < 24; i++ {
+			h := hash64(i + 4<<32)
+			length := 40 + unit(h)*80
+			speed := 120 + w.Wind*16*unit(hash64(h))
+			if w.Kind == sim.Crosswind {
+				x := math.Mod(unit(hash64(h+1))*(width+length)-t*speed, width+length)
+				if x < 0 {
+					x += width + length
+				}
+				streak(x-length, unit(hash64(h+2))*height, length, 1.5, color.RGBA{255, 255, 255, 60})
+			} else {
+				y := math.Mod(unit(hash64(h+1))*(height+length)+t*speed, height+length)
+				streak(unit(hash64(h+2))*width, y-length, 1.5, length, color.RGBA{255, 255, 255, 50})
+			}
+		}
+	case sim.Thermals:
+		const band = 1500.0
+		for i := uint64(0); i < 18; i++ {
+			h := hash64(i + 5<<32)
+			x := unit(h) * width
+			y := math.Mod(unit(hash64(h))*band+t*w.Wind*10, band)
+			sx, sy := cam.ToScreen(x, y)
+			drawCircle(dst, sx+shakeX, sy+shakeY, (10+unit(hash64(h+1))*14)*cam.Zoom, color.RGBA{255, 240, 210, uint8(40 * fade)})
+		}
+	}
+}
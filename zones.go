@@ -0,0 +1,255 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+
+	"gitlab.com/Goodgis/go-game/sim"
+)
+
+// zoneSky is the colour the sky reaches at the top of each of sim.Zones.
+var zoneSky = []color.RGBA{
+	{120, 190, 240, 255},
+	{90, 160, 230, 255},
+	{30, 60, 150, 255},
+	{14, 20, 70, 255},
+	{8, 10, 36, 255},
+	spaceColor,
+}
+
+const zoneBannerDuration = 2.5
+
+const (
+	// skyFloor is where the procedural sky takes over from background.png.
+	skyFloor = 6400.0
+	skySpan  = 8000.0
+)
+
+var (
+	skyGradient *ebiten.Image
+	starfield   *ebiten.Image
+	whitePixel  *ebiten.Image
+	skyTopColor color.RGBA
+	spaceColor  = color.RGBA{4, 6, 18, 255}
+)
+
+const (
+	starsFadeStart = 9000.0
+	starsFadeEnd   = 13000.0
+)
+
+// buildSky samples the top of background.png so the procedural sky picks
+// up where the painted one stops, then blends through each zone's colour.
+func buildSky(bgSource image.Image) {
+	b := bgSource.Bounds()
+	r, gr, bl, _ := bgSource.At(b.Min.X+b.Dx()/2, b.Min.Y).RGBA()
+	skyTopColor = color.RGBA{uint8(r >> 8), uint8(gr >> 8), uint8(bl >> 8), 255}
+
+	const rows = 512
+	grad := image.NewRGBA(image.Rect(0, 0, 1, rows))
+	for y := 0; y < rows; y++ {
+		world := skyFloor + skySpan*float64(rows-1-y)/(rows-1)
+		grad.SetRGBA(0, y, skyColorAt(world-rocketBaseY))
+	}
+	skyGradient = ebiten.NewImageFromImage(grad)
+
+	stars := image.NewRGBA(image.Rect(0, 0, screenWidth, screenHeight))
+	for i := uint64(0); i < 220; i++ {
+		h := hash64(i)
+		v := uint8(140 + h%116)
+		stars.SetRGBA(int(h>>8%uint64(screenWidth)), int(h>>24%uint64(screenHeight)), color.RGBA{v, v, v, v})
+	}
+	starfield = ebiten.NewImageFromImage(stars)
+
+	px := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	px.SetRGBA(0, 0, color.RGBA{255, 255, 255, 255})
+	whitePixel = ebiten.NewImageFromImage(px)
+}
+
+// skyColorAt interpolates the sky colour for an altitude above the top of
+// background.png, easing from its painted colour into each zone's colour.
+func skyColorAt(altitude float64) color.RGBA {
+	from, fromAlt := skyTopColor, skyFloor-rocketBaseY
+	for i, sky := range zoneSky {
+		if i == len(sim.Zones)-1 {
+			return sky
+		}
+		top := sim.Zones[i+1].Floor
+		if top <= fromAlt {
+			continue
+		}
+		if altitude <= top {
+			t := (altitude - fromAlt) / (top - fromAlt)
+			return lerpRGBA(from, sky, math.Max(0, t))
+		}
+		from, fromAlt = sky, top
+	}
+	return spaceColor
+}
+
+func lerpRGBA(a, b color.RGBA, t float64) color.RGBA {
+	return color.RGBA{lerp8(a.R, b.R, t), lerp8(a.G, b.G, t), lerp8(a.B, b.B, t), 255}
+}
+
+func lerp8(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t)
+}
+
+// hash64 is splitmix64, used to scatter procedural scenery deterministically.
+func hash64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// unit maps a hash onto [0, 1).
+func unit(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
+
+// visibleWorldY returns the world heights at the bottom and top of the screen.
+func visibleWorldY(cam Camera) (lo, hi float64) {
+	_, hi = cam.ToWorld(0, 0)
+	_, lo = cam.ToWorld(0, float64(screenHeight))
+	return lo, hi
+}
+
+func drawSky(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(screenWidth)*3, skySpan/float64(skyGradient.Bounds().Dy()))
+	cam.Place(op, -float64(screenWidth), skyFloor+skySpan, shakeX, shakeY)
+	dst.DrawImage(skyGradient, op)
+}
+
+func drawStars(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	alpha := (g.camera.Y - starsFadeStart) / (starsFadeEnd - starsFadeStart)
+	if alpha <= 0 {
+		return
+	}
+	alpha = math.Min(1, alpha)
+	h := float64(screenHeight)
+	// Tile the starfield vertically around whatever part of it is in view.
+	_, top := visibleWorldY(cam)
+	base := math.Floor(top/h) * h
+	for i := -1.0; i <= 2; i++ {
+		for j := -1.0; j <= 1; j++ {
+			op := &ebiten.DrawImageOptions{}
+			cam.Place(op, j*float64(screenWidth), base-i*h+h, shakeX, shakeY)
+			op.ColorScale.ScaleAlpha(float32(alpha))
+			dst.DrawImage(starfield, op)
+		}
+	}
+}
+
+// drawEarth rises the curve of the planet into the bottom of the frame as
+// the rocket passes the Karman line.
+func drawEarth(dst *ebiten.Image, g *Game, _ Camera, shakeX, shakeY float64) {
+	p := (g.camera.Y - sim.Zones[4].Floor) / (sim.Zones[5].Floor + 3000 - sim.Zones[4].Floor)
+	if p <= 0 {
+		return
+	}
+	p = math.Min(1, p)
+	const radius = 1400.0
+	top := float64(screenHeight) + 40 - 220*p
+	cx := float32(float64(screenWidth)/2 + shakeX)
+	cy := float32(top + radius + shakeY)
+	vector.StrokeCircle(dst, cx, cy, radius+6, 10, color.RGBA{90, 170, 255, 90}, true)
+	vector.DrawFilledCircle(dst, cx, cy, radius, color.RGBA{28, 84, 160, 255}, true)
+	vector.StrokeCircle(dst, cx, cy, radius-30, 40, color.RGBA{40, 120, 70, 120}, true)
+}
+
+// drawHighClouds scatters cumulus puffs through the troposphere above the
+// painted cloud band, one cluster per 300m cell.
+func drawHighClouds(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	const cell = 300.0
+	lo, hi := visibleWorldY(cam)
+	lo = math.Max(lo, cloudsY+400)
+	hi = math.Min(hi, sim.Zones[1].Floor+rocketBaseY)
+	puff := color.RGBA{235, 240, 250, 210}
+	if g.flight.Weather.Kind == sim.Storm {
+		puff = color.RGBA{120, 125, 140, 220}
+	}
+	for c := math.Floor(lo / cell); c*cell <= hi; c++ {
+		h := hash64(uint64(c))
+		if h%3 == 0 {
+			continue
+		}
+		span := float64(screenWidth) + 160
+		x := math.Mod(unit(hash64(h))*span+g.skyTime*18, span) - 80
+		y := c*cell + unit(hash64(h+1))*cell
+		for i := uint64(0); i < 5; i++ {
+			hp := hash64(h + 10 + i)
+			r := 28 + unit(hp)*26
+			px := x + (unit(hash64(hp))-0.5)*110
+			py := y + (unit(hash64(hp+1))-0.5)*30
+			sx, sy := cam.ToScreen(px, py)
+			drawCircle(dst, sx+shakeX, sy+shakeY, r*cam.Zoom, puff)
+		}
+	}
+}
+
+// drawJetStream streaks fast-moving wind lines across the jet stream band.
+func drawJetStream(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	lo, hi := visibleWorldY(cam)
+	floor, ceil := sim.Zones[1].Floor+rocketBaseY, sim.Zones[2].Floor+rocketBaseY
+	lo, hi = math.Max(lo, floor), math.Min(hi, ceil)
+	const spacing = 36.0
+	for c := math.Floor(lo / spacing); c*spacing <= hi; c++ {
+		h := hash64(uint64(c) + 1<<32)
+		length := 60 + unit(h)*140
+		speed := 300 + unit(hash64(h))*300
+		span := float64(screenWidth) + length
+		x := math.Mod(unit(hash64(h+1))*span+g.skyTime*speed, span) - length
+		sx, sy := cam.ToScreen(x, c*spacing)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(length*cam.Zoom, 2)
+		op.GeoM.Translate(sx+shakeX, sy+shakeY)
+		op.ColorScale.ScaleWithColor(color.RGBA{255, 255, 255, 70})
+		dst.DrawImage(whitePixel, op)
+	}
+}
+
+// drawHaze lays thin translucent bands through the stratosphere and
+// bluish noctilucent wisps through the mesosphere.
+func drawHaze(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	lo, hi := visibleWorldY(cam)
+	floor, ceil := sim.Zones[2].Floor+rocketBaseY, sim.Zones[4].Floor+rocketBaseY
+	lo, hi = math.Max(lo, floor), math.Min(hi, ceil)
+	const spacing = 140.0
+	for c := math.Floor(lo / spacing); c*spacing <= hi; c++ {
+		h := hash64(uint64(c) + 2<<32)
+		if h%2 == 0 {
+			continue
+		}
+		clr := color.RGBA{200, 210, 255, 28}
+		if c*spacing >= sim.Zones[3].Floor+rocketBaseY {
+			clr = color.RGBA{120, 200, 255, 40}
+		}
+		x := (unit(hash64(h)) - 0.5) * float64(screenWidth)
+		w := float64(screenWidth) * (0.8 + unit(hash64(h+1)))
+		sx, sy := cam.ToScreen(x, c*spacing)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(w*cam.Zoom, (6+unit(hash64(h+2))*10)*cam.Zoom)
+		op.GeoM.Translate(sx+shakeX, sy+shakeY)
+		op.ColorScale.ScaleWithColor(clr)
+		dst.DrawImage(whitePixel, op)
+	}
+}
+
+// drawAirglow paints the green airglow band that marks the Karman line.
+func drawAirglow(dst *ebiten.Image, g *Game, cam Camera, shakeX, shakeY float64) {
+	y := sim.Zones[4].Floor + rocketBaseY
+	for i := 0; i < 6; i++ {
+		sx, sy := cam.ToScreen(-float64(screenWidth), y+float64(i)*6)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(float64(screenWidth)*3*cam.Zoom, 6*cam.Zoom)
+		op.GeoM.Translate(sx+shakeX, sy+shakeY)
+		op.ColorScale.ScaleWithColor(color.RGBA{80, 255, 140, uint8(70 - i*10)})
+		dst.DrawImage(whitePixel, op)
+	}
+}